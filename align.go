@@ -0,0 +1,332 @@
+package fancyfmt
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"unicode/utf8"
+
+	"github.com/dave/dst"
+	"github.com/sirkon/errors"
+)
+
+// alignment describes how columns of a composite literal are to be aligned. It cannot be done with decorations
+// as go/printer collapses spaces within a line, so this is done over the printed source.
+type alignment int
+
+const (
+	// alignTable each element is a struct literal on its own line, its keyed fields are columns
+	alignTable alignment = iota + 1
+
+	// alignGrid elements are laid out in rows, elements with the same index in a row are columns
+	alignGrid
+
+	// alignGridRight the same as alignGrid, just columns are aligned to the right
+	alignGridRight
+
+	// alignMatrix elements are one-line literals, their elements are columns
+	alignMatrix
+
+	// alignMatrixRight the same as alignMatrix, just columns are aligned to the right
+	alignMatrixRight
+)
+
+// alignments composite literals to align
+type alignments map[*dst.CompositeLit]alignment
+
+// alignCell a piece of a line to align with pieces having the same index on other lines
+type alignCell struct {
+	start int
+	end   int
+}
+
+// alignColumns pads columns of composite literals printed from the file into src.
+func alignColumns(src []byte, file *dst.File, aligns alignments) ([]byte, error) {
+	if len(aligns) == 0 {
+		return src, nil
+	}
+
+	// composite literals of the printed source are found by their order as the tree was not changed, only its
+	// decorations
+	var index int
+	marks := map[int]alignment{}
+	dst.Inspect(file, func(node dst.Node) bool {
+		if v, ok := node.(*dst.CompositeLit); ok {
+			if a, ok := aligns[v]; ok {
+				marks[index] = a
+			}
+			index++
+		}
+
+		return true
+	})
+
+	fset := token.NewFileSet()
+	afile, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse formatted source")
+	}
+
+	var pads []alignPad
+	index = 0
+	ast.Inspect(afile, func(node ast.Node) bool {
+		v, ok := node.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+
+		a, ok := marks[index]
+		index++
+		if !ok {
+			return true
+		}
+
+		switch a {
+		case alignTable:
+			pads = append(pads, padRows(src, tableRows(fset, afile, v), false)...)
+		case alignGrid:
+			pads = append(pads, padRows(src, gridRows(fset, afile, v), false)...)
+		case alignGridRight:
+			pads = append(pads, padRows(src, gridRows(fset, afile, v), true)...)
+		case alignMatrix:
+			pads = append(pads, padRows(src, matrixRows(fset, afile, v), false)...)
+		case alignMatrixRight:
+			pads = append(pads, padRows(src, matrixRows(fset, afile, v), true)...)
+		}
+
+		return true
+	})
+
+	sort.SliceStable(pads, func(i, j int) bool {
+		return pads[i].offset < pads[j].offset
+	})
+	var buf bytes.Buffer
+	var last int
+	for _, p := range pads {
+		buf.Write(src[last:p.offset])
+		buf.Write(bytes.Repeat([]byte{' '}, p.width))
+		last = p.offset
+	}
+	buf.Write(src[last:])
+
+	return buf.Bytes(), nil
+}
+
+// alignPad a padding of the given width to insert at the offset
+type alignPad struct {
+	offset int
+	width  int
+}
+
+// padRows computes paddings to make cells with the same index to start at the same column. Cells are padded
+// on the left when they are aligned to the right.
+func padRows(src []byte, rows [][]alignCell, right bool) []alignPad {
+	var widths []int
+	for _, row := range rows {
+		for i, c := range row {
+			if i == len(row)-1 && !right {
+				// the width of the last cell doesn't matter
+				break
+			}
+
+			w := utf8.RuneCount(src[c.start:c.end])
+			if i >= len(widths) {
+				widths = append(widths, w)
+			} else if w > widths[i] {
+				widths[i] = w
+			}
+		}
+	}
+
+	var res []alignPad
+	for _, row := range rows {
+		for i, c := range row {
+			if i == len(row)-1 && !right {
+				break
+			}
+
+			w := utf8.RuneCount(src[c.start:c.end])
+			if w >= widths[i] {
+				continue
+			}
+
+			offset := c.start
+			if !right {
+				offset = row[i+1].start
+			}
+			res = append(res, alignPad{
+				offset: offset,
+				width:  widths[i] - w,
+			})
+		}
+	}
+
+	return res
+}
+
+// tableRows returns rows of keyed struct literals elements of the given literal. Elements having comments,
+// taking several lines, sharing a line with other elements or having keys different from the first suitable
+// element are skipped.
+func tableRows(fset *token.FileSet, file *ast.File, l *ast.CompositeLit) [][]alignCell {
+	var keys []string
+	var res [][]alignCell
+	for i, e := range l.Elts {
+		v, ok := e.(*ast.CompositeLit)
+		if !ok || len(v.Elts) == 0 {
+			continue
+		}
+
+		line := fset.Position(v.Pos()).Line
+		if line != fset.Position(v.End()).Line {
+			continue
+		}
+		if i > 0 && fset.Position(l.Elts[i-1].End()).Line == line {
+			continue
+		}
+		if i < len(l.Elts)-1 && fset.Position(l.Elts[i+1].Pos()).Line == line {
+			continue
+		}
+
+		if hasCommentsAtLines(fset, file, v.Pos(), v.End()) {
+			continue
+		}
+
+		var rowKeys []string
+		var row []alignCell
+		for _, ee := range v.Elts {
+			kv, ok := ee.(*ast.KeyValueExpr)
+			if !ok {
+				break
+			}
+			key, ok := kv.Key.(*ast.Ident)
+			if !ok {
+				break
+			}
+
+			rowKeys = append(rowKeys, key.Name)
+			row = append(row, alignCell{
+				start: fset.Position(kv.Pos()).Offset,
+				end:   fset.Position(kv.End()).Offset + 1, // the comma is a part of a cell
+			})
+		}
+		if len(row) != len(v.Elts) {
+			continue
+		}
+
+		if keys == nil {
+			keys = rowKeys
+		}
+		if !sameStrings(keys, rowKeys) {
+			continue
+		}
+
+		res = append(res, row)
+	}
+
+	return res
+}
+
+// gridRows returns rows of elements of the given literal, nothing is returned if there are comments among
+// elements.
+func gridRows(fset *token.FileSet, file *ast.File, l *ast.CompositeLit) [][]alignCell {
+	if len(l.Elts) == 0 {
+		return nil
+	}
+
+	for _, g := range commentsAtLines(fset, file, l.Elts[0].Pos(), l.Elts[len(l.Elts)-1].End()) {
+		for _, c := range g.List {
+			if !byteAnnotation.MatchString(c.Text) {
+				return nil
+			}
+		}
+	}
+
+	var res [][]alignCell
+	var row []alignCell
+	line := -1
+	for _, e := range l.Elts {
+		pos := fset.Position(e.Pos())
+		if pos.Line != line {
+			if len(row) > 0 {
+				res = append(res, row)
+			}
+			row = nil
+			line = pos.Line
+		}
+
+		row = append(row, alignCell{
+			start: pos.Offset,
+			end:   fset.Position(e.End()).Offset + 1, // the comma is a part of a cell
+		})
+	}
+	res = append(res, row)
+
+	return res
+}
+
+// matrixRows returns elements of inner literals of the given literal as rows. Inner literals having comments or
+// taking several lines are skipped.
+func matrixRows(fset *token.FileSet, file *ast.File, l *ast.CompositeLit) [][]alignCell {
+	var res [][]alignCell
+	for _, e := range l.Elts {
+		v, ok := e.(*ast.CompositeLit)
+		if !ok || len(v.Elts) == 0 {
+			continue
+		}
+
+		if fset.Position(v.Pos()).Line != fset.Position(v.End()).Line {
+			continue
+		}
+		if hasCommentsAtLines(fset, file, v.Pos(), v.End()) {
+			continue
+		}
+
+		// cells have no commas, the last cell of a row has no one and it is measured too when aligned to the right
+		var row []alignCell
+		for _, ee := range v.Elts {
+			row = append(row, alignCell{
+				start: fset.Position(ee.Pos()).Offset,
+				end:   fset.Position(ee.End()).Offset,
+			})
+		}
+		res = append(res, row)
+	}
+
+	return res
+}
+
+// hasCommentsAtLines checks if there are comments at lines between the given positions
+func hasCommentsAtLines(fset *token.FileSet, file *ast.File, start, end token.Pos) bool {
+	return len(commentsAtLines(fset, file, start, end)) > 0
+}
+
+// commentsAtLines returns comments at lines between the given positions
+func commentsAtLines(fset *token.FileSet, file *ast.File, start, end token.Pos) []*ast.CommentGroup {
+	startLine := fset.Position(start).Line
+	endLine := fset.Position(end).Line
+	var res []*ast.CommentGroup
+	for _, g := range file.Comments {
+		line := fset.Position(g.Pos()).Line
+		if line >= startLine && line <= endLine {
+			res = append(res, g)
+		}
+	}
+
+	return res
+}
+
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,283 @@
+// Package analyzer provides fancyfmt formatting as a go/analysis analyzer. Every change formatting would make is
+// reported at the import block or the node it belongs to, with a suggested fix making it.
+package analyzer
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/sirkon/errors"
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/ast/astutil"
+
+	"github.com/sirkon/fancyfmt"
+	"github.com/sirkon/fancyfmt/internal/diff"
+)
+
+// Analyzer reports files fancyfmt formatting with default options would change
+var Analyzer = NewAnalyzer()
+
+// NewAnalyzer creates an analyzer reporting files formatting with the given options would change. The imports
+// grouper is taken from the go.mod file of a package, generated files are skipped.
+func NewAnalyzer(opts ...fancyfmt.Option) *analysis.Analyzer {
+	return &analysis.Analyzer{
+		Name: "fancyfmt",
+		Doc:  "reports code that is not formatted with fancyfmt",
+		Run: func(pass *analysis.Pass) (interface{}, error) {
+			return nil, run(pass, opts)
+		},
+	}
+}
+
+func run(pass *analysis.Pass, opts []fancyfmt.Option) error {
+	var (
+		grouper fancyfmt.ImportsGrouper
+		version string
+	)
+	for _, file := range pass.Files {
+		tfile := pass.Fset.File(file.Pos())
+		if tfile == nil || filepath.Ext(tfile.Name()) != ".go" {
+			continue
+		}
+
+		content, err := os.ReadFile(tfile.Name())
+		if err != nil {
+			return errors.Wrap(err, "read "+tfile.Name())
+		}
+		if tfile.Size() != len(content) || fancyfmt.IsGenerated(content) {
+			// files the package was not parsed from as is, cgo ones for instance, are skipped as well
+			continue
+		}
+
+		if grouper == nil {
+			grouper, err = fancyfmt.ImportsGrouperForDir(filepath.Dir(tfile.Name()))
+			if err != nil {
+				// not a module, no imports are from the current project then
+				grouper = fancyfmt.DefaultImportGroupsWithCurrent("")
+			}
+		}
+		if version == "" {
+			// a version given with options overrides the one of the module
+			version, _ = fancyfmt.GoVersionForDir(filepath.Dir(tfile.Name()))
+			if version != "" {
+				opts = append([]fancyfmt.Option{fancyfmt.WithGoVersion(version)}, opts...)
+			}
+		}
+
+		formatted, err := format(tfile.Name(), content, grouper, opts)
+		if err != nil {
+			return errors.Wrap(err, "format "+tfile.Name())
+		}
+
+		report(pass, file, tfile, string(content), formatted)
+	}
+
+	return nil
+}
+
+func format(name string, content []byte, grouper fancyfmt.ImportsGrouper, opts []fancyfmt.Option) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, name, content, parser.ParseComments)
+	if err != nil {
+		return "", errors.Wrap(err, "parse source")
+	}
+
+	res, err := fancyfmt.Format(fset, file, content, grouper, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := io.ReadAll(res)
+	if err != nil {
+		return "", errors.Wrap(err, "read formatted source")
+	}
+
+	return string(data), nil
+}
+
+// report reports differences between the source and the formatted one, changes of the same node are reported
+// as a single diagnostic.
+func report(pass *analysis.Pass, file *ast.File, tfile *token.File, content string, formatted string) {
+	lines := diff.SplitLines(content)
+	pos := func(line int) token.Pos {
+		if line >= len(lines) {
+			return tfile.Pos(tfile.Size())
+		}
+		return tfile.LineStart(line + 1)
+	}
+
+	imports := importsRange(file)
+	var diags []*analysis.Diagnostic
+	nodes := map[ast.Node]*analysis.Diagnostic{}
+	for _, e := range diff.Lines(content, formatted) {
+		edit := analysis.TextEdit{
+			Pos:     pos(e.Start),
+			End:     pos(e.End),
+			NewText: []byte(e.Text),
+		}
+
+		// insertions are looked up with the line before them as they usually add a tail of a node
+		start := edit.Pos
+		if e.Start == e.End && e.Start > 0 {
+			start = pos(e.Start - 1)
+		}
+		end := edit.End
+		if end > start && e.End > e.Start {
+			end--
+		}
+
+		node, message := enclosingNode(pass.TypesInfo, file, imports, start, end)
+		if d, ok := nodes[node]; ok {
+			d.SuggestedFixes[0].TextEdits = append(d.SuggestedFixes[0].TextEdits, edit)
+			continue
+		}
+
+		d := &analysis.Diagnostic{
+			Pos:     node.Pos(),
+			End:     node.End(),
+			Message: message,
+			SuggestedFixes: []analysis.SuggestedFix{
+				{
+					Message:   "Format with fancyfmt",
+					TextEdits: []analysis.TextEdit{edit},
+				},
+			},
+		}
+		if _, isFile := node.(*ast.File); isFile {
+			d.Pos, d.End = edit.Pos, edit.End
+		}
+		nodes[node] = d
+		diags = append(diags, d)
+	}
+
+	for _, d := range diags {
+		pass.Report(*d)
+	}
+}
+
+// importsRange returns a node spanning import declarations of the file, nil is returned if there are none
+func importsRange(file *ast.File) ast.Node {
+	var first, last ast.Decl
+	for _, decl := range file.Decls {
+		g, ok := decl.(*ast.GenDecl)
+		if !ok || g.Tok != token.IMPORT {
+			break
+		}
+
+		if first == nil {
+			first = g
+		}
+		last = g
+	}
+	if first == nil {
+		return nil
+	}
+
+	return &importBlock{
+		pos: first.Pos(),
+		end: last.End(),
+	}
+}
+
+// importBlock a node covering all import declarations
+type importBlock struct {
+	pos token.Pos
+	end token.Pos
+}
+
+func (b *importBlock) Pos() token.Pos { return b.pos }
+func (b *importBlock) End() token.Pos { return b.end }
+
+// enclosingNode returns the node formatting changes in the given interval belong to and a message to report them
+// with. It is the closest enclosing node of those formatting deals with, or the first of them within the interval if
+// the interval covers whole declarations or statements.
+func enclosingNode(info *types.Info, file *ast.File, imports ast.Node, start token.Pos, end token.Pos) (ast.Node, string) {
+	if imports != nil && end >= imports.Pos() && start <= imports.End() {
+		return imports, "imports are not grouped"
+	}
+
+	path, _ := astutil.PathEnclosingInterval(file, start, end)
+	if len(path) == 0 {
+		return file, "file is not formatted"
+	}
+	if kind := nodeKind(info, path[0]); kind != "" {
+		return path[0], kind + " is not formatted"
+	}
+
+	var inner ast.Node
+	ast.Inspect(path[0], func(node ast.Node) bool {
+		if inner != nil || node == nil || node.End() < start || node.Pos() > end {
+			return false
+		}
+		if nodeKind(info, node) != "" {
+			inner = node
+			return false
+		}
+		return true
+	})
+	if inner != nil {
+		return inner, nodeKind(info, inner) + " is not formatted"
+	}
+
+	for _, node := range path {
+		if kind := nodeKind(info, node); kind != "" {
+			return node, kind + " is not formatted"
+		}
+
+		switch node.(type) {
+		case ast.Decl, *ast.ValueSpec:
+			return node, "declaration is not formatted"
+		case ast.Stmt:
+			return node, "statement is not formatted"
+		}
+	}
+
+	// the interval spans several declarations
+	for _, decl := range file.Decls {
+		if decl.End() >= start && decl.Pos() <= end {
+			return decl, "declaration is not formatted"
+		}
+	}
+
+	return file, "file is not formatted"
+}
+
+func nodeKind(info *types.Info, node ast.Node) string {
+	switch v := node.(type) {
+	case *ast.CompositeLit:
+		return "composite literal"
+	case *ast.CallExpr:
+		sel, ok := v.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return "function call"
+		}
+		if id, ok := sel.X.(*ast.Ident); ok {
+			if _, isPkg := info.Uses[id].(*types.PkgName); isPkg {
+				// a package qualified function
+				return "function call"
+			}
+		}
+		return "method call"
+	case *ast.IndexListExpr:
+		return "generic instantiation"
+	case *ast.BasicLit:
+		return "literal"
+	case *ast.FuncDecl:
+		return "function declaration"
+	case *ast.FuncLit:
+		return "function literal"
+	case *ast.TypeSpec:
+		return "type declaration"
+	case *ast.InterfaceType:
+		return "interface"
+	case *ast.FuncType:
+		return "function signature"
+	default:
+		return ""
+	}
+}
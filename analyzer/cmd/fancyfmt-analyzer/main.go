@@ -0,0 +1,14 @@
+// Command fancyfmt-analyzer runs the fancyfmt analyzer standalone or as a go vet tool:
+//
+//	go vet -vettool=$(which fancyfmt-analyzer) ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/sirkon/fancyfmt/analyzer"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
@@ -0,0 +1,19 @@
+module github.com/sirkon/fancyfmt/analyzer
+
+go 1.25.0
+
+require (
+	github.com/sirkon/errors v0.2.0
+	github.com/sirkon/fancyfmt v0.0.0-00010101000000-000000000000
+	golang.org/x/tools v0.45.0
+)
+
+require (
+	github.com/dave/dst v0.27.3 // indirect
+	github.com/sirkon/jsonexec v0.0.1 // indirect
+	golang.org/x/mod v0.36.0 // indirect
+	golang.org/x/sync v0.20.0 // indirect
+)
+
+// the analyzer is developed along with the formatter
+replace github.com/sirkon/fancyfmt => ../
@@ -0,0 +1,28 @@
+package a
+
+import ( // want "imports are not grouped"
+	"strings"
+	"fmt"
+
+	"os"
+)
+
+func f() {
+	/* want "function call is not formatted" */ fmt.Println(os.Args, strings.ToUpper("a"),
+		"b")
+}
+
+var x = /* want "composite literal is not formatted" */ []byte{1, 2,
+	3}
+
+var y = /* want "composite literal is not formatted" */ []int{1,
+	2}
+
+type t struct{}
+
+func (t) m(a, b int) {}
+
+func g(v t) {
+	/* want "method call is not formatted" */ v.m(1,
+		2)
+}
@@ -0,0 +1,65 @@
+package fancyfmt
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+var byteAnnotation = regexp.MustCompile(`^// \|[ -~]*\|$`)
+
+// isByteType checks if the type of literal elements is a byte-sized one
+func isByteType(elem string) bool {
+	return elem == "int8" || elem == "uint8"
+}
+
+// stripByteAnnotations removes ASCII annotations generated before, so they will not freeze the layout as
+// comments do.
+func stripByteAnnotations(l *dst.CompositeLit) {
+	for _, e := range l.Elts {
+		decs := e.Decorations().End
+		if len(decs) == 1 && byteAnnotation.MatchString(decs[0]) {
+			e.Decorations().End = nil
+		}
+	}
+}
+
+// annotateBytes adds a hexdump-like comment with printable ASCII characters of a row at the end of each row
+// of byte literal.
+func annotateBytes(l *dst.CompositeLit) {
+	values := make([]byte, len(l.Elts))
+	for i, e := range l.Elts {
+		if len(e.Decorations().Start) > 0 || len(e.Decorations().End) > 0 {
+			return
+		}
+
+		v, ok := e.(*dst.BasicLit)
+		if !ok {
+			return
+		}
+
+		value, err := strconv.ParseUint(v.Value, 0, 8)
+		if err != nil {
+			return
+		}
+		values[i] = byte(value)
+	}
+
+	var row strings.Builder
+	for i, e := range l.Elts {
+		if values[i] >= ' ' && values[i] <= '~' {
+			row.WriteByte(values[i])
+		} else {
+			row.WriteByte('.')
+		}
+
+		if i < len(l.Elts)-1 && l.Elts[i+1].Decorations().Before == dst.None {
+			continue
+		}
+
+		e.Decorations().End.Replace("// |" + row.String() + "|")
+		row.Reset()
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"github.com/sirkon/fancyfmt"
+)
+
+// formatFlags flags setting up formatting options, they are shared by the formatter and the LSP server
+type formatFlags struct {
+	ChainBreakAfter      int  `help:"Make method chains with more than this amount of calls multiline, 0 means no limit."`
+	ChainRootOnFirstLine bool `help:"Keep the first link of a multiline method chain at the line of the chain root."`
+	TableLayout          bool `help:"Lay out slices of keyed struct literals as aligned columns."`
+	ByteASCII            bool `help:"Annotate rows of byte literals with their printable ASCII characters."`
+	WideHex              bool `help:"Render grids of wide unsigned integers as zero-padded hex numbers."`
+	LiteralGrids         bool `help:"Lay out slices of short literals or identifiers as grids with aligned columns."`
+	LineWidth            int  `help:"A line width grids are fitted into." default:"120"`
+	FittedGrids          bool `help:"Compute a number of columns of integer grids from the line width instead of fitting them into a square."`
+	PowerOfTwoColumns    bool `help:"Make fitted integer grids to have a number of columns that is a power of two."`
+	NumberAlignment      bool `help:"Right-align columns of integer grids."`
+	MatrixLayout         bool `help:"Lay out literals of arrays or slices as matrices with aligned columns."`
+
+	NormalizeNumbers bool   `help:"Normalize spelling of integer and float literals."`
+	UpperHex         bool   `help:"Use uppercase hex digits for normalized numbers."`
+	DigitSeparators  bool   `help:"Group digits of large normalized decimal and hex integers with _."`
+	GoVersion        string `help:"Go version of modules of formatted files, it is taken from their go.mod files if not set."`
+
+	SortUnions bool `help:"Sort terms of constraint unions of interface elements."`
+
+	CheckEquivalence bool `help:"Check the formatted source has the same syntax tree as the original one, up to positions, comments, imports grouping and spelling of literals."`
+	Verify           bool `help:"Format every file twice and fail if the second pass changes anything. Cannot be combined with --lines-only."`
+}
+
+// options returns formatting options the flags set up
+func (f *formatFlags) options() []fancyfmt.Option {
+	var opts []fancyfmt.Option
+	if f.ChainBreakAfter > 0 {
+		opts = append(opts, fancyfmt.WithChainBreakAfter(f.ChainBreakAfter))
+	}
+	if f.ChainRootOnFirstLine {
+		opts = append(opts, fancyfmt.WithChainRootOnFirstLine())
+	}
+	if f.TableLayout {
+		opts = append(opts, fancyfmt.WithTableLayout())
+	}
+	if f.ByteASCII {
+		opts = append(opts, fancyfmt.WithByteASCII())
+	}
+	if f.WideHex {
+		opts = append(opts, fancyfmt.WithWideHex())
+	}
+	if f.LiteralGrids {
+		opts = append(opts, fancyfmt.WithLiteralGrids())
+	}
+	opts = append(opts, fancyfmt.WithLineWidth(f.LineWidth))
+	if f.FittedGrids {
+		opts = append(opts, fancyfmt.WithFittedGrids())
+	}
+	if f.PowerOfTwoColumns {
+		opts = append(opts, fancyfmt.WithPowerOfTwoColumns())
+	}
+	if f.NumberAlignment {
+		opts = append(opts, fancyfmt.WithNumberAlignment())
+	}
+	if f.MatrixLayout {
+		opts = append(opts, fancyfmt.WithMatrixLayout())
+	}
+	if f.NormalizeNumbers {
+		opts = append(opts, fancyfmt.WithNumberNormalization(f.UpperHex, f.DigitSeparators))
+	}
+	if f.GoVersion != "" {
+		// it takes precedence over versions of modules of formatted files as it goes after them
+		opts = append(opts, fancyfmt.WithGoVersion(f.GoVersion))
+	}
+	if f.SortUnions {
+		opts = append(opts, fancyfmt.WithSortedUnions())
+	}
+	if f.CheckEquivalence {
+		opts = append(opts, fancyfmt.WithEquivalenceCheck())
+	}
+	if f.Verify {
+		opts = append(opts, fancyfmt.WithIdempotenceCheck())
+	}
+
+	return opts
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sirkon/errors"
+)
+
+// gitChange a changed Go file and ranges of its changed lines
+type gitChange struct {
+	path  string
+	lines [][2]int
+}
+
+var hunkHeader = regexp.MustCompile(`^@@ -\d+(?:,\d+)? \+(\d+)(?:,(\d+))? @@`)
+
+// gitChanges returns Go files changed in the index if staged is set or since the given revision otherwise. Files
+// are ordered as git reports them. Staged files having unstaged changes are an error, as line numbers of the index
+// do not match ones of the working tree.
+func gitChanges(staged bool, since string) ([]gitChange, error) {
+	root, err := gitRoot()
+	if err != nil {
+		return nil, errors.Wrap(err, "get repository root")
+	}
+
+	// prefixes are set explicitly, as diff.noprefix and diff.mnemonicPrefix settings change them
+	args := []string{
+		"diff", "--no-color", "--no-ext-diff", "--src-prefix=a/", "--dst-prefix=b/", "--diff-filter=ACMR", "-U0",
+	}
+	if staged {
+		args = append(args, "--cached")
+	} else {
+		args = append(args, since)
+	}
+	args = append(args, "--", "*.go")
+	out, err := git(args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "get changes")
+	}
+
+	res, err := parseGitDiff(root, out)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse changes")
+	}
+
+	if staged {
+		// working tree files are what is formatted, they must be the same as staged ones
+		out, err := git("diff", "--name-only", "-z", "--", "*.go")
+		if err != nil {
+			return nil, errors.Wrap(err, "get unstaged changes")
+		}
+
+		unstaged := map[string]bool{}
+		for _, name := range strings.Split(string(out), "\x00") {
+			if name != "" {
+				unstaged[filepath.Join(root, filepath.FromSlash(name))] = true
+			}
+		}
+		for _, c := range res {
+			if unstaged[c.path] {
+				return nil, errors.Newf("%s has unstaged changes, stage or stash them first", c.path)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// parseGitDiff parses a zero context diff with a/ and b/ prefixes and returns changed files with ranges of their
+// changed lines, paths are joined with the root.
+func parseGitDiff(root string, diff []byte) ([]gitChange, error) {
+	var res []gitChange
+	var header bool
+	scanner := bufio.NewScanner(bytes.NewReader(diff))
+	scanner.Buffer(nil, 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			header = true
+			continue
+		case header && strings.HasPrefix(line, "+++ "):
+			name := strings.TrimPrefix(line, "+++ ")
+			if strings.HasPrefix(name, `"`) {
+				// git quotes names having special characters the way Go does
+				unquoted, err := strconv.Unquote(name)
+				if err != nil {
+					return nil, errors.Wrapf(err, "unquote file name %s", name)
+				}
+				name = unquoted
+			}
+			if !strings.HasPrefix(name, "b/") {
+				// /dev/null of deleted files
+				continue
+			}
+
+			res = append(res, gitChange{
+				path: filepath.Join(root, filepath.FromSlash(strings.TrimPrefix(name, "b/"))),
+			})
+			continue
+		}
+
+		match := hunkHeader.FindStringSubmatch(line)
+		if match == nil || len(res) == 0 {
+			continue
+		}
+		header = false
+
+		start, _ := strconv.Atoi(match[1])
+		count := 1
+		if match[2] != "" {
+			count, _ = strconv.Atoi(match[2])
+		}
+		end := start + count - 1
+		if count == 0 {
+			// lines were deleted after the start one, lines around the deletion are touched
+			end = start + 1
+		}
+
+		last := &res[len(res)-1]
+		last.lines = append(last.lines, [2]int{start, end})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read diff")
+	}
+
+	return res, nil
+}
+
+// gitRoot returns the root directory of the repository
+func gitRoot() (string, error) {
+	root, err := git("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes.TrimSpace(root)), nil
+}
+
+func git(args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, errors.Wrapf(err, "run git %s: %s", strings.Join(args, " "), strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseGitDiff(t *testing.T) {
+	diff := `diff --git a/a.go b/a.go
+index 1111111..2222222 100644
+--- a/a.go
++++ b/a.go
+@@ -3 +3 @@ package a
+-var a = 1
++var a = 2
+@@ -10,0 +11,2 @@ func f() {
++	g()
++++ not a header
+@@ -20,3 +21,0 @@ func h() {
+-	a()
+-	b()
+-	c()
+diff --git a/sub/new.go b/sub/new.go
+new file mode 100644
+index 0000000..3333333
+--- /dev/null
++++ b/sub/new.go
+@@ -0,0 +1,5 @@
++package sub
+diff --git "a/sub/na\tme.go" "b/sub/na\tme.go"
+index 4444444..5555555 100644
+--- "a/sub/na\tme.go"
++++ "b/sub/na\tme.go"
+@@ -1,2 +1 @@
+-package sub
++package sub // \303\244
+`
+
+	changes, err := parseGitDiff("/repo", []byte(diff))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []gitChange{
+		{
+			path:  filepath.FromSlash("/repo/a.go"),
+			lines: [][2]int{{3, 3}, {11, 12}, {21, 22}},
+		},
+		{
+			path:  filepath.FromSlash("/repo/sub/new.go"),
+			lines: [][2]int{{1, 5}},
+		},
+		{
+			path:  filepath.FromSlash("/repo/sub/na\tme.go"),
+			lines: [][2]int{{1, 1}},
+		},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Errorf("unexpected changes %+v, expected %+v", changes, expected)
+	}
+}
+
+func TestGitChangesPrefixSettings(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	for _, setting := range []string{"diff.noprefix", "diff.mnemonicPrefix"} {
+		setting := setting
+		t.Run(setting, func(t *testing.T) {
+			dir, err := filepath.EvalSymlinks(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			run := func(args ...string) {
+				t.Helper()
+
+				cmd := exec.Command("git", args...)
+				cmd.Dir = dir
+				if out, err := cmd.CombinedOutput(); err != nil {
+					t.Fatalf("git %v: %s\n%s", args, err, out)
+				}
+			}
+
+			run("init", "-q")
+			run("config", setting, "true")
+			if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			run("add", "a.go")
+
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chdir(dir); err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = os.Chdir(wd)
+			}()
+
+			changes, err := gitChanges(true, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := []gitChange{
+				{
+					path:  filepath.Join(dir, "a.go"),
+					lines: [][2]int{{1, 1}},
+				},
+			}
+			if !reflect.DeepEqual(changes, expected) {
+				t.Errorf("unexpected changes %+v, expected %+v", changes, expected)
+			}
+		})
+	}
+}
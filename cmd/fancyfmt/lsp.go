@@ -0,0 +1,34 @@
+package main
+
+import (
+	"os"
+
+	"github.com/alecthomas/kong"
+	"github.com/sirkon/message"
+
+	"github.com/sirkon/fancyfmt/internal/lsp"
+)
+
+// runLSP runs the LSP server over stdio with formatting options from the given arguments
+func runLSP(args []string) {
+	var cli struct {
+		formatFlags `embed:""`
+	}
+
+	parser, err := kong.New(
+		&cli,
+		kong.Name("fancyfmt lsp"),
+		kong.Description("Language Server Protocol server over stdio providing fancyfmt formatting."),
+	)
+	if err != nil {
+		message.Fatal(err)
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	server := lsp.NewServer(cli.options())
+	if err := server.Serve(os.Stdin, os.Stdout); err != nil {
+		message.Fatal(err)
+	}
+}
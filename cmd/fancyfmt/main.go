@@ -1,6 +1,7 @@
 package main
 
 import (
+	"go/ast"
 	"go/format"
 	"go/parser"
 	"go/token"
@@ -18,12 +19,26 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "lsp" {
+		runLSP(os.Args[2:])
+		return
+	}
+
 	var cli struct {
-		Write          bool   `short:"w" help:"Write formatted files."`
-		Recursive      bool   `short:"r" help:"Process directories recursively. This options requires -w|--write option to be enabled."`
-		CurrentProject string `short:"c" help:"Use this value as the current project path"`
+		Write            bool     `short:"w" help:"Write formatted files."`
+		Recursive        bool     `short:"r" help:"Process directories recursively. This options requires -w|--write option to be enabled."`
+		CurrentProject   string   `short:"c" help:"Use this value as the current project path"`
+		IncludeGenerated bool     `help:"Format generated files found in directories or with git as well, they are skipped by default. Files given explicitly are always formatted."`
+		Exclude          []string `help:"Skip files and directories matching this glob pattern in recursive and git modes, may be repeated." sep:"none"`
+
+		ResolveTypes bool `help:"Type check packages of formatted files to resolve types of array and slice literals."`
+		formatFlags  `embed:""`
+
+		Staged       bool   `help:"Format Go files changed in the git index instead of the given paths, they must have no unstaged changes. Requires -w|--write option to be enabled."`
+		ChangedSince string `help:"Format Go files changed since the given git revision instead of the given paths. Requires -w|--write option to be enabled."`
+		LinesOnly    bool   `help:"Only format changed lines of files in --staged and --changed-since modes."`
 
-		Paths []string `arg:"" type:"path" help:"Paths to process. May be file or directory if recursive option is enabled, use '-'' to format stdin input."`
+		Paths []string `arg:"" optional:"" type:"path" help:"Paths to process. May be file or directory if recursive option is enabled, use '-'' to format stdin input."`
 	}
 
 	ctx := kong.Parse(&cli)
@@ -31,6 +46,22 @@ func main() {
 	if cli.Recursive && !cli.Write {
 		ctx.Fatalf("recursive options requires write option on")
 	}
+	gitMode := cli.Staged || cli.ChangedSince != ""
+	switch {
+	case cli.Staged && cli.ChangedSince != "":
+		ctx.Fatalf("staged and changed-since options cannot be combined")
+	case gitMode && !cli.Write:
+		ctx.Fatalf("staged and changed-since options require write option on")
+	case gitMode && len(cli.Paths) > 0:
+		ctx.Fatalf("staged and changed-since options cannot be combined with paths")
+	case !gitMode && cli.LinesOnly:
+		ctx.Fatalf("lines-only option requires staged or changed-since option on")
+	case cli.LinesOnly && cli.Verify:
+		// the second pass cannot be limited to the same lines, so nothing would be verified
+		ctx.Fatalf("verify option cannot be combined with lines-only option")
+	case !gitMode && len(cli.Paths) == 0:
+		ctx.Fatalf("expected paths to process")
+	}
 	if len(cli.Paths) > 1 && !cli.Write {
 		ctx.Fatalf("can only process the single path with write option set off, got %d path items", len(cli.Paths))
 	}
@@ -46,6 +77,62 @@ func main() {
 		}
 	}
 
+	opts := cli.options()
+
+	settings := processSettings{
+		recursive:        cli.Recursive,
+		write:            cli.Write,
+		includeGenerated: cli.IncludeGenerated,
+		excludes:         cli.Exclude,
+		keepLines:        cli.LinesOnly,
+	}
+	if cli.ResolveTypes {
+		settings.types = newTypesLoader(cli.LinesOnly)
+	}
+
+	if gitMode {
+		settings.changedFiles = true
+		changes, err := gitChanges(cli.Staged, cli.ChangedSince)
+		if err != nil {
+			message.Fatal(errors.Wrap(err, "get changed files"))
+		}
+		root, err := gitRoot()
+		if err != nil {
+			message.Fatal(errors.Wrap(err, "get repository root"))
+		}
+		filter, err := newWalkFilter(root, cli.Exclude)
+		if err != nil {
+			message.Fatal(errors.Wrap(err, "setup changed files filter"))
+		}
+
+		for _, c := range changes {
+			skip, err := filter.skipFile(c.path)
+			if err != nil {
+				message.Fatal(errors.Wrap(err, "check "+c.path))
+			}
+			if skip {
+				continue
+			}
+
+			fileOpts := opts
+			if cli.LinesOnly {
+				if len(c.lines) == 0 {
+					continue
+				}
+
+				fileOpts = fileOpts[:len(fileOpts):len(fileOpts)]
+				for _, lines := range c.lines {
+					fileOpts = append(fileOpts, fancyfmt.WithLines(lines[0], lines[1]))
+				}
+			}
+
+			if err := process(c.path, settings, importsGrouper, fileOpts); err != nil {
+				message.Fatal(errors.Wrap(err, "process "+c.path))
+			}
+		}
+		return
+	}
+
 	for _, path := range cli.Paths {
 		if filepath.Base(path) == "-" && len(cli.Paths) != 1 {
 			message.Fatal("cannot combine stdin input with files or another stdin inputs")
@@ -53,18 +140,34 @@ func main() {
 	}
 	for _, p := range cli.Paths {
 		if filepath.Base(p) == "-" {
-			if err := processStdin(); err != nil {
+			if err := processStdin(opts); err != nil {
 				message.Fatal(err)
 			}
 			return
 		}
-		if err := process(p, cli.Recursive, cli.Write, importsGrouper); err != nil {
+		if err := process(p, settings, importsGrouper, opts); err != nil {
 			message.Fatal(errors.Wrap(err, "process "+p))
 		}
 	}
 }
 
-func process(path string, recursive bool, write bool, grouper fancyfmt.ImportsGrouper) error {
+// processSettings settings of files processing
+type processSettings struct {
+	recursive        bool
+	write            bool
+	includeGenerated bool
+	changedFiles     bool // paths are files found with git rather than given explicitly
+	excludes         []string
+	keepLines        bool         // keep line numbers of the source, these are what formatting may be limited to
+	types            *typesLoader // resolves types of literals if set
+}
+
+func process(
+	path string,
+	settings processSettings,
+	grouper fancyfmt.ImportsGrouper,
+	opts []fancyfmt.Option,
+) error {
 	var paths []string
 	stat, err := os.Stat(path)
 	if err != nil {
@@ -72,20 +175,33 @@ func process(path string, recursive bool, write bool, grouper fancyfmt.ImportsGr
 	}
 
 	if stat.IsDir() {
-		if !recursive {
+		if !settings.recursive {
 			return errors.New("cannot process directory without recursive enabled")
 		}
-		err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		filter, err := newWalkFilter(path, settings.excludes)
+		if err != nil {
+			return errors.Wrap(err, "setup directory walk")
+		}
+
+		err = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			skip, err := filter.skip(path, info.IsDir())
+			if err != nil {
+				return err
+			}
+
 			if info.IsDir() {
-				_, base := filepath.Split(path)
-				if strings.HasPrefix(base, ".") && base != "." && base != ".." {
+				if skip {
 					return filepath.SkipDir
 				}
 
-				return nil
+				return filter.enter(path)
 			}
 
-			if strings.HasSuffix(path, ".go") {
+			if !skip && strings.HasSuffix(path, ".go") {
 				paths = append(paths, path)
 			}
 
@@ -98,29 +214,50 @@ func process(path string, recursive bool, write bool, grouper fancyfmt.ImportsGr
 		paths = append(paths, path)
 	}
 
+	// generated files given explicitly are formatted, as the output replaces the source without write option
+	skipGenerated := !settings.includeGenerated && (stat.IsDir() || settings.changedFiles)
 	for _, path := range paths {
 		fileContent, err := os.ReadFile(path)
 		if err != nil {
 			return errors.Wrap(err, "read file content")
 		}
 
-		fileContent, err = format.Source(fileContent)
-		if err != nil {
-			return errors.Wrap(err, "apply standard formatting before the further processing")
+		if skipGenerated && fancyfmt.IsGenerated(fileContent) {
+			continue
 		}
 
-		fset := token.NewFileSet()
-		file, err := parser.ParseFile(fset, path, fileContent, parser.AllErrors|parser.ParseComments)
-		if err != nil {
-			return errors.Wrap(err, "parse "+path)
+		var (
+			fset     *token.FileSet
+			file     *ast.File
+			fileOpts = withGoVersion(filepath.Dir(path), opts)
+		)
+		if settings.types != nil {
+			f, err := settings.types.load(path)
+			if err != nil {
+				return errors.Wrap(err, "resolve types of "+path)
+			}
+
+			fset, file, fileContent = settings.types.fset, f.file, f.content
+			fileOpts = append(fileOpts[:len(fileOpts):len(fileOpts)], fancyfmt.WithTypesInfo(f.info))
+		} else {
+			fileContent, err = prepareSource(fileContent, settings.keepLines)
+			if err != nil {
+				return err
+			}
+
+			fset = token.NewFileSet()
+			file, err = parser.ParseFile(fset, path, fileContent, parser.AllErrors|parser.ParseComments)
+			if err != nil {
+				return errors.Wrap(err, "parse "+path)
+			}
 		}
 
-		res, err := fancyfmt.Format(fset, file, fileContent, grouper)
+		res, err := fancyfmt.Format(fset, file, fileContent, grouper, fileOpts...)
 		if err != nil {
 			return errors.Wrap(err, "format "+path)
 		}
 
-		if write {
+		if settings.write {
 			dir, base := filepath.Split(path)
 			tmpFile, err := ioutil.TempFile(dir, base)
 			if err != nil {
@@ -142,7 +279,33 @@ func process(path string, recursive bool, write bool, grouper fancyfmt.ImportsGr
 	return nil
 }
 
-func processStdin() error {
+// withGoVersion prepends the Go version of the module of the directory to options, so the one set explicitly
+// overrides it. Options are returned as is if the version cannot be found.
+func withGoVersion(dir string, opts []fancyfmt.Option) []fancyfmt.Option {
+	version, err := fancyfmt.GoVersionForDir(dir)
+	if err != nil || version == "" {
+		return opts
+	}
+
+	return append([]fancyfmt.Option{fancyfmt.WithGoVersion(version)}, opts...)
+}
+
+// prepareSource applies standard formatting to the source unless its line numbers are to be kept
+func prepareSource(content []byte, keepLines bool) ([]byte, error) {
+	if keepLines {
+		// the output is gofmt-ed anyway, so this is only needed for formatting limited to lines of the source
+		return content, nil
+	}
+
+	res, err := format.Source(content)
+	if err != nil {
+		return nil, errors.Wrap(err, "apply standard formatting before the further processing")
+	}
+
+	return res, nil
+}
+
+func processStdin(opts []fancyfmt.Option) error {
 	input, err := io.ReadAll(os.Stdin)
 	if err != nil {
 		return errors.Wrap(err, "read stdin")
@@ -166,7 +329,7 @@ func processStdin() error {
 	if err != nil {
 		return errors.Wrap(err, "setup imports grouper")
 	}
-	res, err := fancyfmt.Format(fset, file, input, grouper)
+	res, err := fancyfmt.Format(fset, file, input, grouper, withGoVersion(".", opts)...)
 	if err != nil {
 		message.Error(errors.Wrap(err, "apply formatting"))
 		return err
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+
+	"github.com/sirkon/errors"
+)
+
+// typesLoader type checks packages of formatted files. Every package is checked once per directory and its
+// dependencies are imported once with the shared importer.
+type typesLoader struct {
+	fset      *token.FileSet
+	importer  types.Importer
+	keepLines bool
+	dirs      map[string]bool
+	files     map[string]*typedFile
+}
+
+// typedFile a source of a file prepared for formatting, its AST and types info of its package
+type typedFile struct {
+	content []byte
+	file    *ast.File
+	info    *types.Info
+}
+
+func newTypesLoader(keepLines bool) *typesLoader {
+	fset := token.NewFileSet()
+	return &typesLoader{
+		fset:      fset,
+		importer:  importer.ForCompiler(fset, "source", nil),
+		keepLines: keepLines,
+		dirs:      map[string]bool{},
+		files:     map[string]*typedFile{},
+	}
+}
+
+// load returns the file with types info of its package. Type checking errors are ignored as partial types info is
+// still useful.
+func (l *typesLoader) load(path string) (*typedFile, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "get absolute path")
+	}
+
+	dir := filepath.Dir(path)
+	if !l.dirs[dir] {
+		if err := l.loadDir(dir); err != nil {
+			return nil, err
+		}
+		l.dirs[dir] = true
+	}
+	if f, ok := l.files[path]; ok {
+		return f, nil
+	}
+
+	// the file is out of the package with current build constraints, it is checked with package files then
+	f, err := l.parse(path)
+	if err != nil {
+		return nil, err
+	}
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "look for package files")
+	}
+	files := []*typedFile{f}
+	for _, name := range append(pkg.GoFiles, pkg.CgoFiles...) {
+		files = append(files, l.files[filepath.Join(dir, name)])
+	}
+	// package files keep their own types info, they do not depend on this file
+	f.info = l.check(files)
+	l.files[path] = f
+
+	return f, nil
+}
+
+// loadDir parses and checks the package of the directory with its tests
+func (l *typesLoader) loadDir(dir string) error {
+	pkg, err := build.ImportDir(dir, 0)
+	if err != nil {
+		return errors.Wrap(err, "look for package files")
+	}
+
+	parse := func(names ...[]string) ([]*typedFile, error) {
+		var res []*typedFile
+		for _, ns := range names {
+			for _, name := range ns {
+				path := filepath.Join(dir, name)
+				f, err := l.parse(path)
+				if err != nil {
+					return nil, err
+				}
+				l.files[path] = f
+				res = append(res, f)
+			}
+		}
+
+		return res, nil
+	}
+
+	files, err := parse(pkg.GoFiles, pkg.CgoFiles)
+	if err != nil {
+		return err
+	}
+	tests, err := parse(pkg.TestGoFiles)
+	if err != nil {
+		return err
+	}
+	xtests, err := parse(pkg.XTestGoFiles)
+	if err != nil {
+		return err
+	}
+
+	setInfo(tests, l.check(append(files[:len(files):len(files)], tests...)))
+	setInfo(files, l.check(files))
+	setInfo(xtests, l.check(xtests))
+
+	return nil
+}
+
+// parse reads the file and parses it the way it is formatted
+func (l *typesLoader) parse(path string) (*typedFile, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "read "+path)
+	}
+	content, err = prepareSource(content, l.keepLines)
+	if err != nil {
+		return nil, errors.Wrap(err, "prepare "+path)
+	}
+
+	file, err := parser.ParseFile(l.fset, path, content, parser.AllErrors|parser.ParseComments)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse "+path)
+	}
+
+	return &typedFile{
+		content: content,
+		file:    file,
+	}, nil
+}
+
+// check type checks files of a package and returns types info of them
+func (l *typesLoader) check(files []*typedFile) *types.Info {
+	info := &types.Info{
+		Types: map[ast.Expr]types.TypeAndValue{},
+	}
+	if len(files) == 0 {
+		return info
+	}
+
+	asts := make([]*ast.File, len(files))
+	for i, f := range files {
+		asts[i] = f.file
+	}
+
+	cfg := &types.Config{
+		Importer:    l.importer,
+		FakeImportC: true,
+		Error:       func(err error) {},
+	}
+	_, _ = cfg.Check(files[0].file.Name.Name, l.fset, asts, info)
+
+	return info
+}
+
+func setInfo(files []*typedFile, info *types.Info) {
+	for _, f := range files {
+		f.info = info
+	}
+}
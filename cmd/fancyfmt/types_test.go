@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirkon/fancyfmt"
+)
+
+func TestTypesLoader(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a_ign.go": "//go:build ignore\n\npackage p\n\ntype B int\n\nvar m = []B{1, 2,\n\t3}\n",
+		"b.go":     "package p\n\ntype B byte\n\nvar n = []B{1, 2, 3,\n\t4}\n\nvar rows = [][]byte{{1, 2,\n\t3}}\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{
+			name: "b.go",
+			expected: `package p
+
+type B byte
+
+var n = []B{
+	0x01, 0x02,
+	0x03, 0x04,
+}
+
+var rows = [][]byte{{
+	0x01, 0x02,
+	0x03,
+}}
+`,
+		},
+		{
+			// the file is out of the package, it is checked with package files and its own B comes first
+			name: "a_ign.go",
+			expected: `//go:build ignore
+
+package p
+
+type B int
+
+var m = []B{
+	1, 2,
+	3,
+}
+`,
+		},
+	}
+
+	// the excluded file is loaded first, so it must not change types info of package files
+	loader := newTypesLoader(false)
+	for i := len(tests) - 1; i >= 0; i-- {
+		if _, err := loader.load(filepath.Join(dir, tests[i].name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	grouper := fancyfmt.DefaultImportGroupsWithCurrent("")
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := loader.load(filepath.Join(dir, tt.name))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := fancyfmt.Format(loader.fset, f.file, f.content, grouper, fancyfmt.WithTypesInfo(f.info))
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("unexpected result:\n%s", data)
+			}
+		})
+	}
+}
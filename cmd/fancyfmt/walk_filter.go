@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sirkon/errors"
+)
+
+// walkFilter decides which directories and files of a directory walk are to be skipped. It follows the go tool
+// rules (vendor, testdata, directories and files starting with _ or .), .gitignore files and exclude patterns.
+type walkFilter struct {
+	root     string
+	excludes []string
+	ignores  []*gitignore
+}
+
+func newWalkFilter(root string, excludes []string) (*walkFilter, error) {
+	for _, e := range excludes {
+		if _, err := path.Match(e, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid exclude pattern %q", e)
+		}
+	}
+
+	root, err := filepath.Abs(root)
+	if err != nil {
+		return nil, errors.Wrap(err, "get absolute path of "+root)
+	}
+
+	res := &walkFilter{
+		root:     root,
+		excludes: excludes,
+	}
+
+	// .gitignore files of parent directories up to the repository root are applied as well
+	var parents []string
+	for dir := root; ; {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			// not in a repository
+			parents = nil
+			break
+		}
+		dir = parent
+		parents = append(parents, dir)
+	}
+	for i := len(parents) - 1; i >= 0; i-- {
+		if err := res.enter(parents[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return res, nil
+}
+
+// enter loads a .gitignore of the directory if there's one
+func (f *walkFilter) enter(dir string) error {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return errors.Wrap(err, "get absolute path of "+dir)
+	}
+
+	ignore, err := loadGitignore(dir)
+	if err != nil {
+		return errors.Wrap(err, "load .gitignore of "+dir)
+	}
+	if ignore != nil {
+		f.ignores = append(f.ignores, ignore)
+	}
+
+	return nil
+}
+
+// skip checks if the given directory or file is to be skipped
+func (f *walkFilter) skip(p string, isDir bool) (bool, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false, errors.Wrap(err, "get absolute path of "+p)
+	}
+	if abs == f.root {
+		return false, nil
+	}
+
+	base := filepath.Base(abs)
+	if strings.HasPrefix(base, ".") || strings.HasPrefix(base, "_") {
+		return true, nil
+	}
+	if isDir && (base == "vendor" || base == "testdata") {
+		return true, nil
+	}
+
+	rel, err := filepath.Rel(f.root, abs)
+	if err != nil {
+		return false, errors.Wrap(err, "get relative path of "+p)
+	}
+	rel = filepath.ToSlash(rel)
+	for _, e := range f.excludes {
+		if ok, _ := path.Match(e, rel); ok {
+			return true, nil
+		}
+		if ok, _ := path.Match(e, base); ok {
+			return true, nil
+		}
+	}
+
+	// the last matching rule wins, rules of deeper .gitignore files take precedence
+	var ignored bool
+	for _, ignore := range f.ignores {
+		rel, err := filepath.Rel(ignore.dir, abs)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+
+		rel = filepath.ToSlash(rel)
+		for _, r := range ignore.rules {
+			if r.dirOnly && !isDir {
+				continue
+			}
+			if r.re.MatchString(rel) {
+				ignored = !r.negate
+			}
+		}
+	}
+
+	return ignored, nil
+}
+
+// skipFile checks if the file is to be skipped, directories on the way from the root to it are checked and entered
+// as if the root was walked
+func (f *walkFilter) skipFile(p string) (bool, error) {
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return false, errors.Wrap(err, "get absolute path of "+p)
+	}
+	rel, err := filepath.Rel(f.root, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return false, errors.Newf("%s is out of %s", p, f.root)
+	}
+
+	ignores := f.ignores
+	defer func() {
+		f.ignores = ignores
+	}()
+
+	dir := f.root
+	if err := f.enter(dir); err != nil {
+		return false, err
+	}
+	parts := strings.Split(rel, string(filepath.Separator))
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		skip, err := f.skip(dir, true)
+		if err != nil || skip {
+			return skip, err
+		}
+		if err := f.enter(dir); err != nil {
+			return false, err
+		}
+	}
+
+	return f.skip(abs, false)
+}
+
+// gitignore rules of a .gitignore file
+type gitignore struct {
+	dir   string
+	rules []gitignoreRule
+}
+
+type gitignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// loadGitignore loads .gitignore of the directory, nil is returned if there's no one
+func loadGitignore(dir string) (*gitignore, error) {
+	file, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "open .gitignore")
+	}
+	defer file.Close()
+
+	res := &gitignore{
+		dir: dir,
+	}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if r, ok := parseGitignoreRule(scanner.Text()); ok {
+			res.rules = append(res.rules, r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "read .gitignore")
+	}
+
+	return res, nil
+}
+
+// parseGitignoreRule parses a line of .gitignore, false is returned for blank lines and comments
+func parseGitignoreRule(line string) (gitignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || line[0] == '#' {
+		return gitignoreRule{}, false
+	}
+
+	var res gitignoreRule
+	if line[0] == '!' {
+		res.negate = true
+		line = line[1:]
+	} else if line[0] == '\\' {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		res.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return gitignoreRule{}, false
+	}
+
+	// patterns without a slash in the middle match at any level
+	var buf strings.Builder
+	buf.WriteByte('^')
+	if !strings.Contains(line, "/") {
+		buf.WriteString("(?:.*/)?")
+	}
+	line = strings.TrimPrefix(line, "/")
+
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; c {
+		case '*':
+			if strings.HasPrefix(line[i:], "**") && (i == 0 || line[i-1] == '/') {
+				switch {
+				case i+2 == len(line):
+					buf.WriteString(".*")
+					i++
+					continue
+				case line[i+2] == '/':
+					buf.WriteString("(?:.*/)?")
+					i += 2
+					continue
+				}
+			}
+			buf.WriteString("[^/]*")
+		case '?':
+			buf.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				buf.WriteString(`\[`)
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			buf.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(line) {
+				i++
+				buf.WriteString(regexp.QuoteMeta(line[i : i+1]))
+			}
+		default:
+			buf.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	buf.WriteByte('$')
+
+	re, err := regexp.Compile(buf.String())
+	if err != nil {
+		return gitignoreRule{}, false
+	}
+	res.re = re
+
+	return res, true
+}
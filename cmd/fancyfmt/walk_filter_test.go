@@ -0,0 +1,177 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseGitignoreRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		matches []string
+		misses  []string
+		negate  bool
+		dirOnly bool
+	}{
+		{
+			name:    "leading double star",
+			line:    "**/x",
+			matches: []string{"x", "a/x", "a/b/x"},
+			misses:  []string{"ax", "x/a"},
+		},
+		{
+			name:    "trailing double star",
+			line:    "x/**",
+			matches: []string{"x/a", "x/a/b"},
+			misses:  []string{"x", "a/x/b"},
+		},
+		{
+			name:    "inner double star",
+			line:    "a/**/b",
+			matches: []string{"a/b", "a/x/b", "a/x/y/b"},
+			misses:  []string{"a/xb", "c/a/b"},
+		},
+		{
+			name:    "anchored",
+			line:    "/anchored",
+			matches: []string{"anchored"},
+			misses:  []string{"sub/anchored"},
+		},
+		{
+			name:    "directory",
+			line:    "dir/",
+			matches: []string{"dir", "a/dir"},
+			misses:  []string{"dirs"},
+			dirOnly: true,
+		},
+		{
+			name:    "negate",
+			line:    "!negate",
+			matches: []string{"negate", "a/negate"},
+			negate:  true,
+		},
+		{
+			name:    "negated class",
+			line:    "[!a]",
+			matches: []string{"b", "x/b"},
+			misses:  []string{"a", "bb"},
+		},
+		{
+			name:    "wildcard",
+			line:    "*.log",
+			matches: []string{"a.log", "x/a.log", ".log"},
+			misses:  []string{"a.log/x", "a.logs"},
+		},
+		{
+			name:    "path with a slash",
+			line:    "doc/*.txt",
+			matches: []string{"doc/a.txt"},
+			misses:  []string{"doc/x/a.txt", "x/doc/a.txt"},
+		},
+		{
+			name:    "escaped hash",
+			line:    `\#x`,
+			matches: []string{"#x"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			r, ok := parseGitignoreRule(tt.line)
+			if !ok {
+				t.Fatalf("rule %q was not parsed", tt.line)
+			}
+			if r.negate != tt.negate {
+				t.Errorf("negate = %t, expected %t", r.negate, tt.negate)
+			}
+			if r.dirOnly != tt.dirOnly {
+				t.Errorf("dirOnly = %t, expected %t", r.dirOnly, tt.dirOnly)
+			}
+			for _, p := range tt.matches {
+				if !r.re.MatchString(p) {
+					t.Errorf("%q does not match %s", tt.line, p)
+				}
+			}
+			for _, p := range tt.misses {
+				if r.re.MatchString(p) {
+					t.Errorf("%q matches %s", tt.line, p)
+				}
+			}
+		})
+	}
+}
+
+func TestParseGitignoreRuleSkipsBlanks(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "/"} {
+		if _, ok := parseGitignoreRule(line); ok {
+			t.Errorf("%q is parsed as a rule", line)
+		}
+	}
+}
+
+func TestWalkFilterSkip(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		".git/HEAD":           "",
+		".gitignore":          "*.pb.go\n!keep.pb.go\nbuild/\n/top.go\n",
+		"sub/.gitignore":      "local.go\n",
+		"a.go":                "",
+		"top.go":              "",
+		"x.pb.go":             "",
+		"keep.pb.go":          "",
+		"build/b.go":          "",
+		"sub/top.go":          "",
+		"sub/local.go":        "",
+		"sub/gen/g.go":        "",
+		"vendor/v.go":         "",
+		"testdata/t.go":       "",
+		"_hidden/h.go":        "",
+		"excluded/e.go":       "",
+		"other/mocks_test.go": "",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		skip bool
+	}{
+		{path: "a.go"},
+		{path: "top.go", skip: true},
+		{path: "x.pb.go", skip: true},
+		{path: "keep.pb.go"},
+		{path: "build/b.go", skip: true},
+		{path: "sub/top.go"},
+		{path: "sub/local.go", skip: true},
+		{path: "sub/gen/g.go", skip: true},
+		{path: "vendor/v.go", skip: true},
+		{path: "testdata/t.go", skip: true},
+		{path: "_hidden/h.go", skip: true},
+		{path: "excluded/e.go", skip: true},
+		{path: "other/mocks_test.go", skip: true},
+	}
+
+	filter, err := newWalkFilter(root, []string{"excluded", "sub/gen", "*_test.go"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		skip, err := filter.skipFile(filepath.Join(root, filepath.FromSlash(tt.path)))
+		if err != nil {
+			t.Fatalf("check %s: %s", tt.path, err)
+		}
+		if skip != tt.skip {
+			t.Errorf("skip %s = %t, expected %t", tt.path, skip, tt.skip)
+		}
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/sirkon/errors"
 	"github.com/sirkon/jsonexec"
+	"golang.org/x/mod/modfile"
 	"golang.org/x/tools/go/packages"
 )
 
@@ -79,6 +80,66 @@ func DefaultImportsGrouper() (ImportsGrouper, error) {
 	return defaultImportGrouper(curproject), nil
 }
 
+// ImportsGrouperForDir provides the default import grouper for a module the given directory belongs to. The module
+// path is taken from the closest go.mod file, the go tool is not called.
+func ImportsGrouperForDir(dir string) (ImportsGrouper, error) {
+	path, data, err := findGoMod(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	curproject := modfile.ModulePath(data)
+	if curproject == "" {
+		return nil, errors.New("no module path in " + path)
+	}
+
+	return DefaultImportGroupsWithCurrent(curproject), nil
+}
+
+// GoVersionForDir returns a Go version of a module the given directory belongs to, like 1.18. It is taken from the
+// go directive of the closest go.mod file, an empty version is returned if there's no directive.
+func GoVersionForDir(dir string) (string, error) {
+	path, data, err := findGoMod(dir)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := modfile.ParseLax(path, data, nil)
+	if err != nil {
+		return "", errors.Wrap(err, "parse "+path)
+	}
+	if file.Go == nil {
+		return "", nil
+	}
+
+	return file.Go.Version, nil
+}
+
+// findGoMod returns a path and a content of the closest go.mod file of the directory
+func findGoMod(dir string) (string, []byte, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "get absolute path of "+dir)
+	}
+
+	for {
+		path := filepath.Join(dir, "go.mod")
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			return path, data, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", nil, errors.Wrap(err, "read go.mod file in "+dir)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil, errors.New("no go.mod file found")
+		}
+		dir = parent
+	}
+}
+
 // DefaultImportGroupsWithCurrent the same s DefaultImportsGroups just no current package set
 func DefaultImportGroupsWithCurrent(current string) ImportsGrouper {
 	oncer.Do(initStdlibPackages)
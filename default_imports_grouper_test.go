@@ -0,0 +1,70 @@
+package fancyfmt_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirkon/fancyfmt"
+)
+
+func TestGoVersionForDir(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "nested")
+	noVersion := filepath.Join(root, "noversion")
+	for _, dir := range []string{filepath.Join(root, "pkg", "sub"), filepath.Join(nested, "pkg"), noVersion} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		filepath.Join(root, "go.mod"):      "module example.com/root\n\ngo 1.12\n",
+		filepath.Join(nested, "go.mod"):    "module example.com/nested\n\ngo 1.21\n",
+		filepath.Join(noVersion, "go.mod"): "module example.com/noversion\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{
+			name: "module-root",
+			dir:  root,
+			want: "1.12",
+		},
+		{
+			name: "module-package",
+			dir:  filepath.Join(root, "pkg", "sub"),
+			want: "1.12",
+		},
+		{
+			name: "nested-module",
+			dir:  filepath.Join(nested, "pkg"),
+			want: "1.21",
+		},
+		{
+			name: "no-go-directive",
+			dir:  noVersion,
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := fancyfmt.GoVersionForDir(tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got version %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package fancyfmt
+
+import (
+	"go/ast"
+	"go/token"
+	"math"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+const (
+	// directiveOff turns formatting off until directiveOn or the end of file
+	directiveOff = "//fancyfmt:off"
+	// directiveOn turns formatting on after directiveOff
+	directiveOn = "//fancyfmt:on"
+	// directiveIgnore keeps a declaration or a statement it is set on as is
+	directiveIgnore = "//fancyfmt:ignore"
+)
+
+// lineRange a range of lines, bounds are included
+type lineRange struct {
+	start int
+	end   int
+}
+
+// isDirective checks if the comment is the given directive, an explanation may follow it after a space
+func isDirective(comment string, directive string) bool {
+	if !strings.HasPrefix(comment, directive) {
+		return false
+	}
+
+	rest := comment[len(directive):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '\t'
+}
+
+// hasIgnoreDirective checks if there's an ignore directive in the node's start or end decorations
+func hasIgnoreDirective(node dst.Node) bool {
+	decs := node.Decorations()
+	for _, d := range decs.Start {
+		if isDirective(d, directiveIgnore) {
+			return true
+		}
+	}
+	for _, d := range decs.End {
+		if isDirective(d, directiveIgnore) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// offRegions returns ranges of lines between off and on directives of the file
+func offRegions(fset *token.FileSet, file *ast.File) []lineRange {
+	var res []lineRange
+	start := -1
+	for _, g := range file.Comments {
+		for _, c := range g.List {
+			switch {
+			case isDirective(c.Text, directiveOff):
+				if start < 0 {
+					start = fset.Position(c.Pos()).Line
+				}
+			case isDirective(c.Text, directiveOn):
+				if start >= 0 {
+					res = append(res, lineRange{
+						start: start,
+						end:   fset.Position(c.Pos()).Line,
+					})
+					start = -1
+				}
+			}
+		}
+	}
+	if start >= 0 {
+		res = append(res, lineRange{
+			start: start,
+			end:   math.MaxInt,
+		})
+	}
+
+	return res
+}
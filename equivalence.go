@@ -0,0 +1,321 @@
+package fancyfmt
+
+import (
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirkon/errors"
+)
+
+// checkEquivalence parses the formatted source and checks if its AST is the same as the original one. Positions,
+// the layout of comments, the order and grouping of imports, the order of union terms and the spelling of literals
+// are not taken into account. Comment texts are compared regardless of their places.
+func checkEquivalence(fset *token.FileSet, orig *ast.File, formatted []byte) error {
+	resFset := token.NewFileSet()
+	res, err := parser.ParseFile(resFset, "", formatted, parser.ParseComments)
+	if err != nil {
+		return errors.Wrap(err, "parse formatted source")
+	}
+
+	if m := compareFiles(orig, res); m != nil {
+		return errors.Newf("%s: %s", fset.Position(m.node.Pos()), m.what)
+	}
+
+	return nil
+}
+
+// astMismatch a difference between trees, the node is from the original one
+type astMismatch struct {
+	node ast.Node
+	what string
+}
+
+var (
+	posType          = reflect.TypeOf(token.NoPos)
+	objectType       = reflect.TypeOf((*ast.Object)(nil))
+	scopeType        = reflect.TypeOf((*ast.Scope)(nil))
+	commentGroupType = reflect.TypeOf((*ast.CommentGroup)(nil))
+	basicLitType     = reflect.TypeOf((*ast.BasicLit)(nil))
+	fieldType        = reflect.TypeOf((*ast.Field)(nil))
+	nodeType         = reflect.TypeOf((*ast.Node)(nil)).Elem()
+)
+
+func compareFiles(orig *ast.File, res *ast.File) *astMismatch {
+	if orig.Name.Name != res.Name.Name {
+		return &astMismatch{
+			node: orig.Name,
+			what: fmt.Sprintf("package name %s turned into %s", orig.Name.Name, res.Name.Name),
+		}
+	}
+
+	origImports, origDecls := splitImports(orig.Decls)
+	resImports, resDecls := splitImports(res.Decls)
+	if !sameStrings(origImports, resImports) {
+		node := ast.Node(orig.Name)
+		if len(orig.Imports) > 0 {
+			node = orig.Imports[0]
+		}
+		return &astMismatch{
+			node: node,
+			what: fmt.Sprintf("imports %v turned into %v", origImports, resImports),
+		}
+	}
+
+	if len(origDecls) != len(resDecls) {
+		return &astMismatch{
+			node: orig,
+			what: fmt.Sprintf("%d declarations turned into %d", len(origDecls), len(resDecls)),
+		}
+	}
+	for i := range origDecls {
+		if m := compareValues(reflect.ValueOf(origDecls[i]), reflect.ValueOf(resDecls[i]), origDecls[i]); m != nil {
+			return m
+		}
+	}
+
+	return compareComments(orig, res)
+}
+
+// compareComments compares comment texts of files as multisets. Whitespaces are not taken into account as gofmt
+// reformats doc comments, byte annotations are skipped as they are regenerated.
+func compareComments(orig *ast.File, res *ast.File) *astMismatch {
+	origTexts := commentTexts(orig)
+	resTexts := commentTexts(res)
+
+	left := map[string]int{}
+	for _, c := range resTexts {
+		left[c.text]++
+	}
+	for _, c := range origTexts {
+		if left[c.text] == 0 {
+			return &astMismatch{
+				node: c.comment,
+				what: fmt.Sprintf("comment %q is lost", c.comment.Text),
+			}
+		}
+		left[c.text]--
+	}
+
+	left = map[string]int{}
+	for _, c := range origTexts {
+		left[c.text]++
+	}
+	for _, c := range resTexts {
+		if left[c.text] == 0 {
+			return &astMismatch{
+				node: orig,
+				what: fmt.Sprintf("comment %q is added", c.comment.Text),
+			}
+		}
+		left[c.text]--
+	}
+
+	return nil
+}
+
+// commentText a comment with its text with whitespaces collapsed
+type commentText struct {
+	comment *ast.Comment
+	text    string
+}
+
+func commentTexts(file *ast.File) []commentText {
+	var res []commentText
+	for _, g := range file.Comments {
+		for _, c := range g.List {
+			if byteAnnotation.MatchString(c.Text) {
+				continue
+			}
+
+			res = append(res, commentText{
+				comment: c,
+				text:    strings.Join(strings.Fields(c.Text), " "),
+			})
+		}
+	}
+
+	return res
+}
+
+// splitImports returns sorted imports of leading import declarations and the rest of declarations
+func splitImports(decls []ast.Decl) ([]string, []ast.Decl) {
+	var imports []string
+	for i, decl := range decls {
+		g, ok := decl.(*ast.GenDecl)
+		if !ok || g.Tok != token.IMPORT {
+			sort.Strings(imports)
+			return imports, decls[i:]
+		}
+
+		for _, spec := range g.Specs {
+			s := spec.(*ast.ImportSpec)
+			path, _ := strconv.Unquote(s.Path.Value)
+			if s.Name != nil {
+				path = s.Name.Name + " " + path
+			}
+			imports = append(imports, path)
+		}
+	}
+
+	sort.Strings(imports)
+	return imports, nil
+}
+
+// compareValues compares parts of trees, the node is the closest original one to report a difference at
+func compareValues(x reflect.Value, y reflect.Value, node ast.Node) *astMismatch {
+	switch x.Type() {
+	case posType, objectType, scopeType, commentGroupType:
+		return nil
+	}
+	if x.Kind() == reflect.Interface {
+		if x.IsNil() || y.IsNil() {
+			if x.IsNil() != y.IsNil() {
+				return &astMismatch{
+					node: node,
+					what: fmt.Sprintf("%s changed", nodeName(node)),
+				}
+			}
+			return nil
+		}
+
+		x, y = x.Elem(), y.Elem()
+		if x.Type() != y.Type() {
+			return &astMismatch{
+				node: nodeOf(x, node),
+				what: fmt.Sprintf("%s turned into %s", nodeName(nodeOf(x, node)), nodeName(nodeOf(y, node))),
+			}
+		}
+	}
+
+	switch x.Kind() {
+	case reflect.Ptr:
+		if x.IsNil() || y.IsNil() {
+			if x.IsNil() != y.IsNil() {
+				return &astMismatch{
+					node: node,
+					what: fmt.Sprintf("%s changed", nodeName(node)),
+				}
+			}
+			return nil
+		}
+
+		node = nodeOf(x, node)
+		if x.Type() == basicLitType {
+			return compareLiterals(x.Interface().(*ast.BasicLit), y.Interface().(*ast.BasicLit))
+		}
+		if x.Type() == fieldType {
+			if m, ok := compareUnions(x.Interface().(*ast.Field), y.Interface().(*ast.Field)); ok {
+				return m
+			}
+		}
+		return compareValues(x.Elem(), y.Elem(), node)
+
+	case reflect.Struct:
+		for i := 0; i < x.NumField(); i++ {
+			if m := compareValues(x.Field(i), y.Field(i), node); m != nil {
+				return m
+			}
+		}
+		return nil
+
+	case reflect.Slice:
+		if x.Len() != y.Len() {
+			return &astMismatch{
+				node: node,
+				what: fmt.Sprintf("a number of elements of %s changed from %d to %d", nodeName(node), x.Len(), y.Len()),
+			}
+		}
+		for i := 0; i < x.Len(); i++ {
+			if m := compareValues(x.Index(i), y.Index(i), node); m != nil {
+				return m
+			}
+		}
+		return nil
+
+	default:
+		if x.Interface() != y.Interface() {
+			return &astMismatch{
+				node: node,
+				what: fmt.Sprintf("%s changed: %v turned into %v", nodeName(node), x.Interface(), y.Interface()),
+			}
+		}
+		return nil
+	}
+}
+
+// compareLiterals compares literals by their values
+func compareLiterals(x *ast.BasicLit, y *ast.BasicLit) *astMismatch {
+	if x.Kind == y.Kind {
+		xv := constant.MakeFromLiteral(x.Value, x.Kind, 0)
+		yv := constant.MakeFromLiteral(y.Value, y.Kind, 0)
+		if xv.Kind() != constant.Unknown && yv.Kind() != constant.Unknown && constant.Compare(xv, token.EQL, yv) {
+			return nil
+		}
+	}
+
+	return &astMismatch{
+		node: x,
+		what: fmt.Sprintf("literal %s turned into %s", x.Value, y.Value),
+	}
+}
+
+// compareUnions compares constraint unions of interface elements as sets of terms, false is returned if fields
+// are not unions.
+func compareUnions(x *ast.Field, y *ast.Field) (*astMismatch, bool) {
+	if len(x.Names) > 0 || len(y.Names) > 0 {
+		return nil, false
+	}
+
+	xterms := unionTermTexts(x.Type)
+	yterms := unionTermTexts(y.Type)
+	if len(xterms) < 2 || len(yterms) < 2 {
+		return nil, false
+	}
+
+	if !sameStrings(xterms, yterms) {
+		return &astMismatch{
+			node: x,
+			what: fmt.Sprintf("union terms %v turned into %v", xterms, yterms),
+		}, true
+	}
+
+	return nil, true
+}
+
+// unionTermTexts returns sorted texts of union terms
+func unionTermTexts(x ast.Expr) []string {
+	var res []string
+	for {
+		v, ok := x.(*ast.BinaryExpr)
+		if !ok || v.Op != token.OR {
+			res = append(res, types.ExprString(x))
+			break
+		}
+
+		res = append(res, types.ExprString(v.Y))
+		x = v.X
+	}
+
+	sort.Strings(res)
+	return res
+}
+
+func nodeOf(v reflect.Value, def ast.Node) ast.Node {
+	if v.Type().Implements(nodeType) {
+		return v.Interface().(ast.Node)
+	}
+
+	return def
+}
+
+func nodeName(node ast.Node) string {
+	return fmt.Sprintf("%T", node)
+}
@@ -0,0 +1,120 @@
+package fancyfmt
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func TestCompareFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		orig string
+		res  string
+		line int    // a line of the original node the mismatch is reported at, 0 if there's no mismatch
+		what string // a description of the mismatch
+	}{
+		{
+			name: "layout",
+			orig: "package a\n\nimport (\n\t\"os\"\n\t\"fmt\"\n)\n\nvar x = []int{1, 2,\n\t3} // x\n",
+			res:  "package a\n\nimport (\n\t\"fmt\"\n\n\t\"os\"\n)\n\n// x\nvar x = []int{\n\t1, 2,\n\t3,\n}\n",
+		},
+		{
+			name: "literal spelling",
+			orig: "package a\n\nvar x = []byte{1, 255}\n",
+			res:  "package a\n\nvar x = []byte{0x01, 0xff}\n",
+		},
+		{
+			name: "union order",
+			orig: "package a\n\ntype N interface {\n\t~int | ~uint\n}\n",
+			res:  "package a\n\ntype N interface {\n\t~uint |\n\t\t~int\n}\n",
+		},
+		{
+			name: "byte annotations and doc comment spacing",
+			orig: "package a\n\n//  Doc\nvar x = []byte{\n\t0x41, // |B|\n}\n",
+			res:  "package a\n\n// Doc\nvar x = []byte{\n\t0x41, // |A|\n}\n",
+		},
+		{
+			name: "literal value",
+			orig: "package a\n\nvar x = []int{\n\t1,\n\t2,\n}\n",
+			res:  "package a\n\nvar x = []int{\n\t1,\n\t3,\n}\n",
+			line: 5,
+			what: "literal 2 turned into 3",
+		},
+		{
+			name: "element count",
+			orig: "package a\n\nvar x = []int{1, 2}\n",
+			res:  "package a\n\nvar x = []int{1}\n",
+			line: 3,
+			what: "a number of elements of *ast.CompositeLit changed from 2 to 1",
+		},
+		{
+			name: "node type",
+			orig: "package a\n\nfunc f() {\n\tg(a)\n}\n",
+			res:  "package a\n\nfunc f() {\n\tg(a.b)\n}\n",
+			line: 4,
+			what: "*ast.Ident turned into *ast.SelectorExpr",
+		},
+		{
+			name: "imports",
+			orig: "package a\n\nimport \"os\"\n",
+			res:  "package a\n\nimport \"fmt\"\n",
+			line: 3,
+			what: "imports [os] turned into [fmt]",
+		},
+		{
+			name: "lost comment",
+			orig: "package a\n\nvar x = 1\n\n// y is\nvar y = 2\n",
+			res:  "package a\n\nvar x = 1\n\nvar y = 2\n",
+			line: 5,
+			what: `comment "// y is" is lost`,
+		},
+		{
+			name: "changed comment",
+			orig: "package a\n\nvar x = 1 // one\n",
+			res:  "package a\n\nvar x = 1 // two\n",
+			line: 3,
+			what: `comment "// one" is lost`,
+		},
+		{
+			name: "added comment",
+			orig: "package a\n\nvar x = 1\n",
+			res:  "package a\n\n// x\nvar x = 1\n",
+			line: 1,
+			what: `comment "// x" is added`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fset := token.NewFileSet()
+			orig, err := parser.ParseFile(fset, "orig.go", tt.orig, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res, err := parser.ParseFile(token.NewFileSet(), "res.go", tt.res, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			m := compareFiles(orig, res)
+			if tt.line == 0 {
+				if m != nil {
+					t.Errorf("unexpected mismatch at line %d: %s", fset.Position(m.node.Pos()).Line, m.what)
+				}
+				return
+			}
+
+			if m == nil {
+				t.Fatal("mismatch is not detected")
+			}
+			if line := fset.Position(m.node.Pos()).Line; line != tt.line {
+				t.Errorf("mismatch is reported at line %d, expected %d", line, tt.line)
+			}
+			if m.what != tt.what {
+				t.Errorf("mismatch is described as %q, expected %q", m.what, tt.what)
+			}
+		})
+	}
+}
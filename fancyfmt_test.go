@@ -0,0 +1,87 @@
+package fancyfmt_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirkon/fancyfmt"
+	"github.com/sirkon/fancyfmt/fancyfmttest"
+)
+
+func TestFormat(t *testing.T) {
+	grouper := fancyfmt.DefaultImportGroupsWithCurrent("github.com/sirkon/fancyfmt")
+	tests := []struct {
+		name string
+		opts []fancyfmt.Option
+	}{
+		{
+			name: "default",
+		},
+		{
+			name: "chains",
+			opts: []fancyfmt.Option{fancyfmt.WithChainBreakAfter(3), fancyfmt.WithChainRootOnFirstLine()},
+		},
+		{
+			name: "tables",
+			opts: []fancyfmt.Option{fancyfmt.WithTableLayout()},
+		},
+		{
+			name: "bytes",
+			opts: []fancyfmt.Option{fancyfmt.WithByteASCII(), fancyfmt.WithWideHex()},
+		},
+		{
+			name: "grids",
+			opts: []fancyfmt.Option{fancyfmt.WithLiteralGrids(), fancyfmt.WithFittedGrids(), fancyfmt.WithNumberAlignment()},
+		},
+		{
+			name: "matrix",
+			opts: []fancyfmt.Option{fancyfmt.WithMatrixLayout()},
+		},
+		{
+			name: "numbers",
+			opts: []fancyfmt.Option{fancyfmt.WithNumberNormalization(false, true)},
+		},
+		{
+			name: "numbers_go112",
+			opts: []fancyfmt.Option{fancyfmt.WithNumberNormalization(false, true), fancyfmt.WithGoVersion("1.12")},
+		},
+		{
+			name: "powers",
+			opts: []fancyfmt.Option{
+				fancyfmt.WithFittedGrids(),
+				fancyfmt.WithPowerOfTwoColumns(),
+				fancyfmt.WithNumberNormalization(false, true),
+			},
+		},
+		{
+			name: "unions",
+			opts: []fancyfmt.Option{fancyfmt.WithSortedUnions()},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			fancyfmttest.Run(t, filepath.Join("testdata", tt.name), grouper, tt.opts...)
+		})
+	}
+}
+
+func TestInternalTestdata(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("internal", "testdata", "testdata.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grouper := fancyfmt.DefaultImportGroupsWithCurrent("github.com/sirkon/fancyfmt")
+	res, err := fancyfmt.Source(src, grouper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res) != string(src) {
+		t.Errorf("internal/testdata/testdata.go is not formatted:\n%s", res)
+	}
+	fancyfmttest.AssertIdempotent(t, src, grouper)
+	fancyfmttest.AssertEquivalent(t, src, grouper)
+}
@@ -0,0 +1,112 @@
+// Package fancyfmttest provides helpers to test formatters built with fancyfmt. Golden tests format every
+// testdata/*.input file and compare the result with the matching *.golden one:
+//
+//	func TestFormat(t *testing.T) {
+//		fancyfmttest.Run(t, "testdata", fancyfmt.DefaultImportGroupsWithCurrent("example.com/project"))
+//	}
+//
+// Run tests with -fancyfmttest.update flag to regenerate golden files.
+package fancyfmttest
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sirkon/fancyfmt"
+	"github.com/sirkon/fancyfmt/internal/diff"
+)
+
+// the flag is namespaced not to collide with ones of tests using the package
+var update = flag.Bool("fancyfmttest.update", false, "update golden files of fancyfmttest.Run")
+
+// Run formats every *.input file of the directory with the grouper and options and compares results with
+// *.golden files, they are written instead with -fancyfmttest.update flag. Formatting is checked to be idempotent
+// and to keep the syntax tree as well.
+func Run(t *testing.T, dir string, grouper fancyfmt.ImportsGrouper, opts ...fancyfmt.Option) {
+	t.Helper()
+
+	inputs, err := filepath.Glob(filepath.Join(dir, "*.input"))
+	if err != nil {
+		t.Fatalf("look for input files in %s: %s", dir, err)
+	}
+	if len(inputs) == 0 {
+		t.Fatalf("no input files in %s", dir)
+	}
+	sort.Strings(inputs)
+
+	opts = append(opts[:len(opts):len(opts)], fancyfmt.WithEquivalenceCheck(), fancyfmt.WithIdempotenceCheck())
+	for _, input := range inputs {
+		input := input
+		name := strings.TrimSuffix(filepath.Base(input), ".input")
+		t.Run(name, func(t *testing.T) {
+			src, err := os.ReadFile(input)
+			if err != nil {
+				t.Fatalf("read input: %s", err)
+			}
+
+			res, err := fancyfmt.Source(src, grouper, opts...)
+			if err != nil {
+				t.Fatalf("format %s: %s", input, err)
+			}
+
+			golden := strings.TrimSuffix(input, ".input") + ".golden"
+			if *update {
+				if err := os.WriteFile(golden, res, 0644); err != nil {
+					t.Fatalf("update golden file: %s", err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("read golden file, run tests with -fancyfmttest.update flag to create it: %s", err)
+			}
+			if d := lineDiff(string(expected), string(res)); d != "" {
+				t.Errorf("%s does not match %s:\n%s", input, golden, d)
+			}
+		})
+	}
+}
+
+// AssertIdempotent checks formatting the source once more does not change it
+func AssertIdempotent(t testing.TB, src []byte, grouper fancyfmt.ImportsGrouper, opts ...fancyfmt.Option) {
+	t.Helper()
+
+	opts = append(opts[:len(opts):len(opts)], fancyfmt.WithIdempotenceCheck())
+	if _, err := fancyfmt.Source(src, grouper, opts...); err != nil {
+		t.Errorf("formatting is not idempotent: %s", err)
+	}
+}
+
+// AssertEquivalent checks formatting the source does not change its syntax tree and comment texts, up to positions,
+// places of comments, imports grouping and spelling of literals.
+func AssertEquivalent(t testing.TB, src []byte, grouper fancyfmt.ImportsGrouper, opts ...fancyfmt.Option) {
+	t.Helper()
+
+	opts = append(opts[:len(opts):len(opts)], fancyfmt.WithEquivalenceCheck())
+	if _, err := fancyfmt.Source(src, grouper, opts...); err != nil {
+		t.Errorf("formatting changes the syntax tree: %s", err)
+	}
+}
+
+// lineDiff returns differing lines of texts, the result is empty if they are the same
+func lineDiff(expected string, actual string) string {
+	lines := diff.SplitLines(expected)
+	var buf strings.Builder
+	for _, e := range diff.Lines(expected, actual) {
+		_, _ = fmt.Fprintf(&buf, "@@ line %d\n", e.Start+1)
+		for _, l := range lines[e.Start:e.End] {
+			buf.WriteString("-" + strings.TrimSuffix(l, "\n") + "\n")
+		}
+		for _, l := range diff.SplitLines(e.Text) {
+			buf.WriteString("+" + strings.TrimSuffix(l, "\n") + "\n")
+		}
+	}
+
+	return buf.String()
+}
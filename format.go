@@ -14,16 +14,94 @@ import (
 )
 
 // Format formats given AST tree
-func Format(fset *token.FileSet, file *ast.File, content []byte, grouper ImportsGrouper) (io.Reader, error) {
-	dfile, err := decorator.DecorateFile(fset, file)
+func Format(
+	fset *token.FileSet,
+	file *ast.File,
+	content []byte,
+	grouper ImportsGrouper,
+	opts ...Option,
+) (io.Reader, error) {
+	o := newOptions(opts)
+	res, err := formatFile(fset, file, content, grouper, o)
+	if err != nil {
+		return nil, err
+	}
+
+	if o.equivalenceCheck {
+		if err := checkEquivalence(fset, file, res); err != nil {
+			return nil, errors.Wrap(err, "check formatted source is equivalent to the original one")
+		}
+	}
+	if o.idempotenceCheck && len(o.lines) == 0 {
+		if err := checkIdempotence(file, res, grouper, o); err != nil {
+			return nil, errors.Wrap(err, "check formatting is idempotent")
+		}
+	}
+
+	return bytes.NewReader(res), nil
+}
+
+func formatFile(
+	fset *token.FileSet,
+	file *ast.File,
+	content []byte,
+	grouper ImportsGrouper,
+	opts *options,
+) ([]byte, error) {
+	dec := decorator.NewDecorator(fset)
+	dfile, err := dec.DecorateFile(file)
 	if err != nil {
 		return nil, errors.Wrap(err, "get ast decoration")
 	}
 
+	st := newFormatState(opts, fset, file, content, dec.Map.Ast.Nodes)
+	if importsFormattable(dfile, st) {
+		groupImports(dfile, grouper)
+	}
+
+	if err := formatMultiline(dfile, st); err != nil {
+		return nil, errors.Wrap(err, "set up multiline formatting")
+	}
+
+	var buf bytes.Buffer
+	if err := decorator.Fprint(&buf, dfile); err != nil {
+		return nil, errors.Wrap(err, "format result")
+	}
+
+	res, err := alignColumns(buf.Bytes(), dfile, st.aligns)
+	if err != nil {
+		return nil, errors.Wrap(err, "align columns")
+	}
+
+	return res, nil
+}
+
+// importsFormattable checks if imports are not turned off for formatting with directives
+func importsFormattable(file *dst.File, st *formatState) bool {
+	for _, decl := range file.Decls {
+		g, ok := decl.(*dst.GenDecl)
+		if !ok || g.Tok != token.IMPORT {
+			break
+		}
+
+		if hasIgnoreDirective(g) {
+			return false
+		}
+		if overlaps, _ := st.frozen(g); overlaps {
+			return false
+		}
+	}
+
+	return true
+}
+
+// groupImports joins imports in one declaration, except "C", and splits them in groups with the grouper
+func groupImports(dfile *dst.File, grouper ImportsGrouper) {
 	// Ищем первую ноду не import "C"
 	impStart := -1
 	impFinish := impStart
 	var imports []dst.Spec
+	var decs dst.GenDeclDecorations
 	for i, decl := range dfile.Decls {
 		g, ok := decl.(*dst.GenDecl)
 		if !ok {
@@ -47,6 +125,15 @@ func Format(fset *token.FileSet, file *ast.File, content []byte, grouper Imports
 		}
 
 		imports = append(imports, g.Specs...)
+
+		// comments of joined declarations are kept
+		if impStart == i {
+			decs.Before = g.Decs.Before
+		}
+		decs.Start = append(decs.Start, g.Decs.Start...)
+		decs.Tok = append(decs.Tok, g.Decs.Tok...)
+		decs.Lparen = append(decs.Lparen, g.Decs.Lparen...)
+		decs.End = append(decs.End, g.Decs.End...)
 	}
 	if impStart >= 0 {
 		dfile.Decls = append(dfile.Decls[:impStart], dfile.Decls[impFinish+1:]...)
@@ -68,6 +155,8 @@ func Format(fset *token.FileSet, file *ast.File, content []byte, grouper Imports
 	})
 	for i, spec := range imports {
 		if i == 0 {
+			// the original spacing after it is dropped as well
+			spec.(*dst.ImportSpec).Decorations().After = dst.NewLine
 			continue
 		}
 
@@ -89,21 +178,12 @@ func Format(fset *token.FileSet, file *ast.File, content []byte, grouper Imports
 			Lparen: true,
 			Specs:  imports,
 			Rparen: true,
+			Decs:   decs,
 		})
 		decls = append(decls, dfile.Decls[impStart:]...)
 
 		dfile.Decls = decls
 	}
-	if err := formatMultiline(dfile); err != nil {
-		return nil, errors.Wrap(err, "set up multiline formatting")
-	}
-
-	var buf bytes.Buffer
-	if err := decorator.Fprint(&buf, dfile); err != nil {
-		return nil, errors.Wrap(err, "format result")
-	}
-
-	return &buf, nil
 }
 
 func unqoute(v string) string {
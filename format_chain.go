@@ -0,0 +1,104 @@
+package fancyfmt
+
+import (
+	"github.com/dave/dst"
+)
+
+// chain represents a chain of selectors, calls, indices, type assertions, etc. like
+//
+//	q.Where(cond)[0].(Builder).Build()
+type chain struct {
+	root  dst.Expr
+	links []*dst.SelectorExpr // from the innermost to the outermost one
+	calls int
+}
+
+// multilineChain treats the whole chain as one unit: if any of its links breaks every link goes on its own line.
+// Nodes of processed chains are remembered in seen to not process their subchains again.
+func multilineChain(x dst.Expr, opts *options, seen map[dst.Node]struct{}) {
+	if _, ok := seen[x]; ok {
+		return
+	}
+
+	c := collectChain(x, seen)
+	if len(c.links) < 2 {
+		return
+	}
+
+	var isMultiline bool
+	for _, link := range c.links {
+		if chainLinkBroken(link) {
+			isMultiline = true
+			break
+		}
+	}
+	if opts.chainBreakCalls > 0 && c.calls > opts.chainBreakCalls {
+		isMultiline = true
+	}
+	if !isMultiline {
+		return
+	}
+
+	for i, link := range c.links {
+		if i == 0 {
+			if opts.chainKeepRoot {
+				unbreakChainLink(link)
+				continue
+			}
+
+			if _, ok := link.X.(*dst.Ident); ok {
+				// this is either a package or a receiver, both look better with the first link at the same line
+				continue
+			}
+		}
+
+		breakChainLink(link)
+	}
+}
+
+func collectChain(x dst.Expr, seen map[dst.Node]struct{}) chain {
+	var res chain
+	for res.root == nil {
+		seen[x] = struct{}{}
+		switch v := x.(type) {
+		case *dst.SelectorExpr:
+			res.links = append(res.links, v)
+			x = v.X
+		case *dst.TypeAssertExpr:
+			// go/printer never breaks a line before a type assertion, so it is just a part of a link
+			x = v.X
+		case *dst.CallExpr:
+			res.calls++
+			x = v.Fun
+		case *dst.IndexExpr:
+			x = v.X
+		case *dst.IndexListExpr:
+			x = v.X
+		case *dst.ParenExpr:
+			x = v.X
+		default:
+			delete(seen, x)
+			res.root = x
+		}
+	}
+
+	for i, j := 0, len(res.links)-1; i < j; i, j = i+1, j-1 {
+		res.links[i], res.links[j] = res.links[j], res.links[i]
+	}
+
+	return res
+}
+
+func chainLinkBroken(link *dst.SelectorExpr) bool {
+	return link.Sel.Decorations().Before != dst.None
+}
+
+func breakChainLink(link *dst.SelectorExpr) {
+	if link.Sel.Decorations().Before == dst.None {
+		link.Sel.Decorations().Before = dst.NewLine
+	}
+}
+
+func unbreakChainLink(link *dst.SelectorExpr) {
+	link.Sel.Decorations().Before = dst.None
+}
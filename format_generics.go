@@ -0,0 +1,135 @@
+package fancyfmt
+
+import (
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// multilineIndices puts every type argument of an explicit generic instantiation at its own line if any of them is
+// or if the instantiation ends beyond the line width.
+func multilineIndices(v *dst.IndexListExpr, st *formatState) {
+	var isMultiline bool
+	for _, e := range v.Indices {
+		if e.Decorations().Start != nil || e.Decorations().End != nil {
+			// exit if there's a comment
+			return
+		}
+
+		if e.Decorations().Before == dst.NewLine {
+			isMultiline = true
+		}
+	}
+	if !isMultiline {
+		col, ok := st.endColumn(v)
+		isMultiline = ok && col > st.opts.lineWidth
+	}
+
+	if !isMultiline {
+		return
+	}
+
+	for _, e := range v.Indices {
+		e.Decorations().Before = dst.NewLine
+		e.Decorations().After = dst.NewLine
+	}
+}
+
+// multilineUnion puts every term of a constraint union of an interface element at its own line with | at the end of
+// a line if any of them is or if the union ends beyond the line width. Terms are sorted when it is set up with
+// options.
+func multilineUnion(f *dst.Field, st *formatState) {
+	terms, ops := unionTerms(f.Type)
+	if len(ops) == 0 {
+		return
+	}
+
+	var isMultiline bool
+	for _, op := range ops {
+		if op.Decorations().Start != nil || op.Decorations().End != nil || op.Decs.Op != nil {
+			// exit if there's a comment
+			return
+		}
+	}
+	for _, t := range terms {
+		if t.Decorations().Start != nil || t.Decorations().End != nil {
+			return
+		}
+
+		if t.Decorations().Before != dst.None || t.Decorations().After != dst.None {
+			isMultiline = true
+		}
+	}
+	if !isMultiline {
+		col, ok := st.endColumn(f.Type)
+		isMultiline = ok && col > st.opts.lineWidth
+	}
+
+	if st.opts.sortUnions {
+		sortUnionTerms(terms, ops, st)
+	}
+
+	for i, t := range terms {
+		t.Decorations().Before = dst.None
+		t.Decorations().After = dst.None
+		if isMultiline && i > 0 {
+			t.Decorations().Before = dst.NewLine
+		}
+	}
+}
+
+// unionTerms returns terms of a union and binary expressions joining them, the first one joins the first two terms
+func unionTerms(x dst.Expr) ([]dst.Expr, []*dst.BinaryExpr) {
+	v, ok := x.(*dst.BinaryExpr)
+	if !ok || v.Op != token.OR {
+		return []dst.Expr{x}, nil
+	}
+
+	terms, ops := unionTerms(v.X)
+	return append(terms, v.Y), append(ops, v)
+}
+
+// sortUnionTerms sorts terms of a union by their text, nothing is done if some of them are not from the source
+func sortUnionTerms(terms []dst.Expr, ops []*dst.BinaryExpr, st *formatState) {
+	texts := map[dst.Expr]string{}
+	for _, t := range terms {
+		text, ok := st.source(t)
+		if !ok {
+			return
+		}
+		texts[t] = strings.TrimPrefix(text, "~")
+	}
+
+	sort.SliceStable(terms, func(i, j int) bool {
+		return texts[terms[i]] < texts[terms[j]]
+	})
+
+	ops[0].X = terms[0]
+	for i, op := range ops {
+		op.Y = terms[i+1]
+	}
+}
+
+// receiverTypeParams returns type parameters of the method's receiver if there are several of them, they are kept
+// as is.
+func receiverTypeParams(v *dst.FuncDecl) *dst.IndexListExpr {
+	if v.Recv == nil || len(v.Recv.List) != 1 {
+		return nil
+	}
+
+	typ := v.Recv.List[0].Type
+	for {
+		switch x := typ.(type) {
+		case *dst.StarExpr:
+			typ = x.X
+		case *dst.ParenExpr:
+			typ = x.X
+		case *dst.IndexListExpr:
+			return x
+		default:
+			return nil
+		}
+	}
+}
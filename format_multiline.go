@@ -7,14 +7,44 @@ import (
 	"strings"
 
 	"github.com/dave/dst"
+	"github.com/sirkon/errors"
 )
 
 const (
 	widthLimit = 16
 )
 
-func formatMultiline(file *dst.File) error {
+// hexWidths natural widths of hex representations of unsigned integers
+var hexWidths = map[string]int{
+	"int8":   2,
+	"uint8":  2,
+	"uint16": 4,
+	"uint32": 8,
+	"uint64": 16,
+}
+
+func formatMultiline(file *dst.File, st *formatState) error {
+	opts := st.opts
+	grids, err := collectGridDirectives(file, st)
+	if err != nil {
+		return errors.Wrap(err, "collect grid directives")
+	}
+
+	receivers := map[*dst.IndexListExpr]struct{}{}
 	dst.Inspect(file, func(node dst.Node) bool {
+		if node == nil {
+			return true
+		}
+		if hasIgnoreDirective(node) || !st.touched(node) {
+			return false
+		}
+		if overlaps, inside := st.frozen(node); inside {
+			return false
+		} else if overlaps {
+			// the node has parts formatting is turned off for, so only its children outside of these are formatted
+			return true
+		}
+
 		switch v := node.(type) {
 		case *dst.TypeSpec:
 			multilineTypeParams(v.TypeParams)
@@ -24,7 +54,12 @@ func formatMultiline(file *dst.File) error {
 		case *dst.FuncDecl:
 			multilineFuncDeclParams(v)
 			multilineFuncDeclResults(v)
+			if l := receiverTypeParams(v); l != nil {
+				receivers[l] = struct{}{}
+			}
 		case *dst.CallExpr:
+			multilineChain(v, opts, st.chains)
+
 			var isMultiline bool
 			for _, p := range v.Args {
 				if p.Decorations().End != nil {
@@ -43,6 +78,18 @@ func formatMultiline(file *dst.File) error {
 				p.Decorations().After = dst.NewLine
 			}
 		case *dst.CompositeLit:
+			typ := resolveLiteralType(v, opts, st.nodes)
+			if opts.tableLayout && isTableLiteral(v, typ) {
+				st.aligns[v] = alignTable
+			}
+			grid, hasGrid := grids[v]
+			if (opts.byteASCII || grid.ascii) && isByteType(typ.elem) {
+				stripByteAnnotations(v)
+			}
+			if hasGrid && directedGrid(v, grid, typ, st) {
+				return true
+			}
+
 			var isMultiline bool
 			for _, v := range v.Elts {
 				if v.Decorations().End != nil {
@@ -57,15 +104,39 @@ func formatMultiline(file *dst.File) error {
 			if !isMultiline {
 				return true
 			}
-			if possibleFormatting(v) {
+			if opts.matrixLayout && matrixLayout(v, typ, st) {
+				return true
+			}
+			if possibleFormatting(v, typ, st) {
 				return true
 			}
 			for _, p := range v.Elts {
 				p.Decorations().Before = dst.NewLine
 				p.Decorations().After = dst.NewLine
 			}
+		case *dst.BasicLit:
+			if opts.numberNormalization {
+				normalizeNumber(v, opts)
+			}
 		case *dst.SelectorExpr:
-			multilineSelector(v)
+			multilineChain(v, opts, st.chains)
+		case *dst.TypeAssertExpr:
+			multilineChain(v, opts, st.chains)
+		case *dst.IndexExpr:
+			multilineChain(v, opts, st.chains)
+		case *dst.InterfaceType:
+			for _, f := range v.Methods.List {
+				if len(f.Names) == 0 {
+					multilineUnion(f, st)
+				}
+			}
+		case *dst.IndexListExpr:
+			multilineChain(v, opts, st.chains)
+			if _, isReceiver := receivers[v]; !isReceiver {
+				multilineIndices(v, st)
+			}
+		case *dst.ParenExpr:
+			multilineChain(v, opts, st.chains)
 		}
 
 		return true
@@ -113,97 +184,274 @@ func multilineFuncDeclResults(v *dst.FuncDecl) {
 	return
 }
 
-func possibleFormatting(l *dst.CompositeLit) bool {
+func possibleFormatting(l *dst.CompositeLit, typ literalType, st *formatState) bool {
 	if len(l.Elts) == 0 {
 		return false
 	}
 
-	if l.Type == nil {
+	// only arrays are supported
+	if !typ.array {
 		return false
 	}
 
-	// only arrays are supported
-	v, ok := l.Type.(*dst.ArrayType)
-	if !ok {
-		return false
+	opts := st.opts
+	if opts.literalGrids && !isIntegerType(typ.elem) && literalGrid(l, st) {
+		return true
 	}
 
 	if ensureFormat(l) {
+		base, prefix, ok := integerLiteralsBase(l)
+		ok = ok && isIntegerType(typ.elem)
+		if ok {
+			padBinaryOrOctal(l, base, prefix)
+		}
+		if opts.byteASCII && isByteType(typ.elem) {
+			annotateBytes(l)
+		}
+		if ok && opts.numberAlignment {
+			st.aligns[l] = alignGridRight
+		}
 		return true
 	}
 
 	// exit if an array type is not one of integer number types
-	id, ok := v.Elt.(*dst.Ident)
-	if !ok {
-		return false
-	}
 	var width int
-	switch id.Name {
-	case "byte", "int8", "uint8":
+	switch typ.elem {
+	case "int8", "uint8":
 		width = 2
-	case "int", "int16", "int32", "int64", "uint", "uint16", "uint32", "uint64":
+	case "uint16", "uint32", "uint64":
+		if opts.wideHex {
+			width = hexWidths[typ.elem]
+		}
+	case "int", "int16", "int32", "int64", "uint":
 	default:
 		return false
 	}
 
-	// checks if all elements are actual integers: expressions, constants, etc are not allowed, only right integer
-	// numbers
+	base, prefix, ok := integerLiteralsBase(l)
+	if !ok {
+		return false
+	}
+	if padBinaryOrOctal(l, base, prefix) || hasNegativeIntegers(l) {
+		width = 0
+	}
+
+	for _, el := range l.Elts {
+		if width == 0 {
+			break
+		}
+
+		// replace byte number into its hex representation
+		v, _ := integerLiteral(el)
+		value, _ := strconv.ParseUint(v.Value, 0, 64)
+		resval := strconv.FormatUint(value, 16)
+		if len(resval) < width {
+			resval = strings.Repeat("0", width-len(resval)) + resval
+		}
+		v.Value = "0x" + resval
+	}
+
+	if opts.fittedGrids {
+		gridLayout(l, integerGridColumns(l, typ, st))
+	} else {
+		gridLayout(l, squareColumns(l))
+	}
+	if opts.byteASCII && isByteType(typ.elem) {
+		annotateBytes(l)
+	}
+	if opts.numberAlignment {
+		st.aligns[l] = alignGridRight
+	}
+
+	return true
+}
+
+// integerLiteralsBase checks if all elements are actual integers: expressions, constants, etc are not allowed,
+// only right integer numbers. Returns their common base and prefix then.
+func integerLiteralsBase(l *dst.CompositeLit) (base int, prefix string, ok bool) {
 	for _, e := range l.Elts {
 		// don't fix anything if there's a comment
 		if len(e.Decorations().Start) > 0 || len(e.Decorations().End) > 0 {
-			return false
+			return 0, "", false
 		}
-		switch v := e.(type) {
-		case *dst.BasicLit:
-			if v.Kind != token.INT {
-				return false
-			}
-			if strings.HasPrefix(v.Value, "0") && v.Value != "0" && !strings.HasPrefix(v.Value, "0x") {
-				// уже используется бинарное либо 8-ричное предтавление чиселек, такое не трогаем, оно наверняка не
-				// просто так
-				return false
-			}
-		default:
-			return false
+		v, ok := integerLiteral(e)
+		if !ok {
+			return 0, "", false
+		}
+
+		b, p := literalBase(v.Value)
+		switch {
+		case b == 0:
+		case base == 0:
+			base = b
+			prefix = p
+		case b == 16 && base == 10 || b == 10 && base == 16:
+			// hex and decimal numbers are mixed in byte grids
+			base = 10
+		case b != base:
+			// binary or octal numbers mixed with numbers of other bases, this is hardly a table to format
+			return 0, "", false
+		}
+	}
+
+	return base, prefix, true
+}
+
+// padBinaryOrOctal makes binary and octal numbers to be of equal width keeping their base as they are used
+// for a reason
+func padBinaryOrOctal(l *dst.CompositeLit, base int, prefix string) bool {
+	if base != 2 && base != 8 {
+		return false
+	}
+
+	padIntegers(l, base, prefix, 0)
+	return true
+}
+
+// integerLiteral returns an integer literal of an element, negative numbers are allowed as well
+func integerLiteral(e dst.Expr) (*dst.BasicLit, bool) {
+	if v, ok := e.(*dst.UnaryExpr); ok && v.Op == token.SUB {
+		e = v.X
+	}
+
+	v, ok := e.(*dst.BasicLit)
+	if !ok || v.Kind != token.INT {
+		return nil, false
+	}
+
+	return v, true
+}
+
+// hasNegativeIntegers checks if there are negative numbers among elements
+func hasNegativeIntegers(l *dst.CompositeLit) bool {
+	for _, e := range l.Elts {
+		if _, ok := e.(*dst.UnaryExpr); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// literalBase returns a base of an integer literal and its prefix. Zero base is returned for zero as it fits
+// any base.
+func literalBase(value string) (int, string) {
+	switch {
+	case value == "0":
+		return 0, ""
+	case len(value) < 2 || value[0] != '0':
+		return 10, ""
+	}
+
+	switch value[1] {
+	case 'x', 'X':
+		return 16, "0x"
+	case 'b', 'B':
+		return 2, "0b"
+	case 'o', 'O':
+		return 8, "0o"
+	default:
+		// legacy octal representation like 0755
+		return 8, "0"
+	}
+}
+
+// padIntegers renders integer literals in the given base zero-padding them to the widest one but not less than
+// to minWidth digits. Decimal numbers are not padded as this turns them into octal ones.
+func padIntegers(l *dst.CompositeLit, base int, prefix string, minWidth int) {
+	digits := make([]string, len(l.Elts))
+	width := minWidth
+	for i, e := range l.Elts {
+		v, _ := integerLiteral(e)
+		value, _ := strconv.ParseUint(v.Value, 0, 64)
+		digits[i] = strconv.FormatUint(value, base)
+		if len(digits[i]) > width {
+			width = len(digits[i])
+		}
+	}
+
+	for i, e := range l.Elts {
+		v, _ := integerLiteral(e)
+		if base == 10 || len(digits[i]) >= width {
+			v.Value = prefix + digits[i]
+			continue
 		}
+
+		v.Value = prefix + strings.Repeat("0", width-len(digits[i])) + digits[i]
 	}
+}
 
-	// ensure formatting
+// squareColumns returns a number of columns to fit all elements into a square up to 16 elements in width
+// and height.
+func squareColumns(l *dst.CompositeLit) int {
 	sq := int(math.Floor(math.Sqrt(float64(len(l.Elts))) + 0.9999))
 	if sq > widthLimit {
 		sq = widthLimit
 	}
 
-	// tries to fit all numbers into a square up to 16 elements in width and height.
-	for i, el := range l.Elts {
-		if i%sq == 0 {
-			el.Decorations().Before = dst.NewLine
-		} else {
-			el.Decorations().Before = dst.None
-		}
+	return sq
+}
 
-		if i == len(l.Elts)-1 {
-			el.Decorations().After = dst.NewLine
-		} else {
-			el.Decorations().After = dst.None
+// integerGridColumns computes a number of columns of integer grid from the line width and the width of
+// its widest element
+func integerGridColumns(l *dst.CompositeLit, typ literalType, st *formatState) int {
+	var width int
+	for _, e := range l.Elts {
+		v, _ := integerLiteral(e)
+		w := len(v.Value)
+		if st.opts.numberNormalization {
+			// elements are normalized after the literal, digit separators they may get are counted in advance
+			w = len(normalizeInteger(v.Value, st.opts))
 		}
-		if width == 0 {
-			continue
+		if _, ok := e.(*dst.UnaryExpr); ok {
+			w++
 		}
+		if w > width {
+			width = w
+		}
+	}
 
-		// replace byte number into its hex representation
-		v := el.(*dst.BasicLit)
-		var value uint64
-		if strings.HasPrefix(v.Value, "0x") {
-			value, _ = strconv.ParseUint(v.Value[2:], 16, 64)
-		} else {
-			value, _ = strconv.ParseUint(v.Value, 10, 64)
+	var res int
+	if st.opts.byteASCII && isByteType(typ.elem) {
+		// every item takes one more character in the annotation and the annotation itself needs " // ||"
+		res = (st.opts.lineWidth - st.indent(l) - 6) / (width + 3)
+	} else {
+		res = fittingColumns(l, width, st)
+	}
+
+	if st.opts.powerOfTwoColumns {
+		pow := 1
+		for pow*2 <= res {
+			pow *= 2
 		}
-		resval := strconv.FormatUint(value, 16)
-		if len(resval) < width {
-			resval = strings.Repeat("0", width-len(resval)) + resval
+		res = pow
+	}
+	if res > len(l.Elts) {
+		res = len(l.Elts)
+	}
+	if res < 1 {
+		res = 1
+	}
+
+	return res
+}
+
+// isTableLiteral checks if this is a slice or an array of keyed struct literals like the ones used for table
+// driven tests
+func isTableLiteral(l *dst.CompositeLit, typ literalType) bool {
+	if !typ.array {
+		return false
+	}
+
+	for _, e := range l.Elts {
+		v, ok := e.(*dst.CompositeLit)
+		if !ok || len(v.Elts) == 0 {
+			return false
+		}
+
+		if _, ok := v.Elts[0].(*dst.KeyValueExpr); !ok {
+			return false
 		}
-		v.Value = "0x" + resval
 	}
 
 	return true
@@ -240,53 +488,11 @@ func ensureFormat(l *dst.CompositeLit) bool {
 	}
 
 	// the first line setups array width, make every line to have the same length except, most probably, the last one
-	for i, el := range l.Elts {
-		if i%lineWidth == 0 {
-			el.Decorations().Before = dst.NewLine
-		} else {
-			el.Decorations().Before = dst.None
-		}
-
-		if i == len(l.Elts)-1 {
-			el.Decorations().After = dst.NewLine
-		} else {
-			el.Decorations().After = dst.None
-		}
-	}
+	gridLayout(l, lineWidth)
 
 	return true
 }
 
-func multilineSelector(x *dst.SelectorExpr) bool {
-	var hasNewLine bool
-	switch v := x.X.(type) {
-	case *dst.CallExpr:
-		hasNewLine = multlineSelectorFunc(v)
-	case *dst.SelectorExpr:
-		hasNewLine = multilineSelector(v)
-	}
-	if x.Sel.Decorations().Before == dst.NewLine {
-		return true
-	}
-	if hasNewLine {
-		x.Sel.Decorations().Before = dst.NewLine
-		return true
-	}
-
-	return false
-}
-
-func multlineSelectorFunc(x *dst.CallExpr) bool {
-	switch v := x.Fun.(type) {
-	case *dst.CallExpr:
-		return multlineSelectorFunc(v)
-	case *dst.SelectorExpr:
-		return multilineSelector(v)
-	}
-
-	return false
-}
-
 func multilineFuncTypeParams(v *dst.FuncType) {
 	var isMultiline bool
 	for _, p := range v.Params.List {
@@ -0,0 +1,43 @@
+package fancyfmt
+
+import (
+	"go/parser"
+	"go/token"
+	"io"
+
+	"github.com/sirkon/errors"
+)
+
+// FormatRange formats the source applying formatting rules only to nodes overlapping lines from startLine to
+// endLine, both are included and counted from 1. Imports are grouped as a whole regardless of the range, the rest
+// of the source is printed as gofmt does.
+func FormatRange(
+	src []byte,
+	startLine int,
+	endLine int,
+	grouper ImportsGrouper,
+	opts ...Option,
+) ([]byte, error) {
+	if startLine < 1 || endLine < startLine {
+		return nil, errors.Newf("invalid line range %d-%d", startLine, endLine)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.AllErrors|parser.ParseComments)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse source")
+	}
+
+	opts = append(opts[:len(opts):len(opts)], WithLines(startLine, endLine))
+	formatted, err := Format(fset, file, src, grouper, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := io.ReadAll(formatted)
+	if err != nil {
+		return nil, errors.Wrap(err, "read formatted source")
+	}
+
+	return res, nil
+}
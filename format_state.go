@@ -0,0 +1,180 @@
+package fancyfmt
+
+import (
+	"go/ast"
+	"go/token"
+
+	"github.com/dave/dst"
+)
+
+// tabWidth a width of an indentation level used to estimate line widths
+const tabWidth = 4
+
+// formatState holds what formatting rules may need beyond the node itself
+type formatState struct {
+	opts    *options
+	fset    *token.FileSet
+	content []byte
+	nodes   map[dst.Node]ast.Node // maps decorated nodes into the original ones
+	aligns  alignments
+	chains  map[dst.Node]struct{}
+	off     []lineRange // regions formatting is turned off at
+}
+
+func newFormatState(
+	opts *options,
+	fset *token.FileSet,
+	file *ast.File,
+	content []byte,
+	nodes map[dst.Node]ast.Node,
+) *formatState {
+	return &formatState{
+		opts:    opts,
+		fset:    fset,
+		content: content,
+		nodes:   nodes,
+		aligns:  alignments{},
+		chains:  map[dst.Node]struct{}{},
+		off:     offRegions(fset, file),
+	}
+}
+
+// lines returns a range of lines the node takes in the original source, false is returned for nodes not coming
+// from it.
+func (s *formatState) lines(node dst.Node) (lineRange, bool) {
+	orig, ok := s.nodes[node]
+	if !ok || orig == nil || !orig.Pos().IsValid() {
+		return lineRange{}, false
+	}
+
+	return lineRange{
+		start: s.fset.Position(orig.Pos()).Line,
+		end:   s.fset.Position(orig.End()).Line,
+	}, true
+}
+
+// line returns a line the node starts at in the original source, 0 is returned for nodes not coming from it.
+func (s *formatState) line(node dst.Node) int {
+	lines, _ := s.lines(node)
+	return lines.start
+}
+
+// frozen checks if the node overlaps regions formatting is turned off at, inside is set when the node is
+// completely within one of them.
+func (s *formatState) frozen(node dst.Node) (overlaps bool, inside bool) {
+	lines, ok := s.lines(node)
+	if !ok {
+		return false, false
+	}
+
+	for _, r := range s.off {
+		if lines.end < r.start || lines.start > r.end {
+			continue
+		}
+
+		if lines.start >= r.start && lines.end <= r.end {
+			return true, true
+		}
+		overlaps = true
+	}
+
+	return overlaps, false
+}
+
+// touched checks if the node overlaps line ranges formatting is limited to. Every node does if there are no limits.
+func (s *formatState) touched(node dst.Node) bool {
+	if len(s.opts.lines) == 0 {
+		return true
+	}
+
+	lines, ok := s.lines(node)
+	if !ok {
+		return true
+	}
+	for _, r := range s.opts.lines {
+		if lines.end >= r.start && lines.start <= r.end {
+			return true
+		}
+	}
+
+	return false
+}
+
+// source returns the original text of the node, false is returned for nodes not coming from the source
+func (s *formatState) source(node dst.Node) (string, bool) {
+	orig, ok := s.nodes[node]
+	if !ok || orig == nil || !orig.Pos().IsValid() {
+		return "", false
+	}
+
+	file := s.fset.File(orig.Pos())
+	if file == nil {
+		return "", false
+	}
+	start, end := file.Offset(orig.Pos()), file.Offset(orig.End())
+	if end > len(s.content) {
+		return "", false
+	}
+
+	return string(s.content[start:end]), true
+}
+
+// endColumn returns a width of the original source line the node ends at up to the node's end, tabs are counted as
+// tabWidth. False is returned for nodes not coming from the source.
+func (s *formatState) endColumn(node dst.Node) (int, bool) {
+	orig, ok := s.nodes[node]
+	if !ok || orig == nil || !orig.End().IsValid() {
+		return 0, false
+	}
+
+	file := s.fset.File(orig.End())
+	if file == nil {
+		return 0, false
+	}
+	end := file.Offset(orig.End())
+	start := file.Offset(file.LineStart(file.Line(orig.End())))
+	if end > len(s.content) {
+		return 0, false
+	}
+
+	var res int
+	for _, c := range string(s.content[start:end]) {
+		if c == '\t' {
+			res += tabWidth
+		} else {
+			res++
+		}
+	}
+
+	return res, true
+}
+
+// indent returns an estimated width of the indentation of the given node's children: the indentation of the line
+// the node starts at in the original source plus one level.
+func (s *formatState) indent(node dst.Node) int {
+	orig, ok := s.nodes[node]
+	if !ok || !orig.Pos().IsValid() {
+		return tabWidth
+	}
+
+	file := s.fset.File(orig.Pos())
+	if file == nil {
+		return tabWidth
+	}
+	pos := file.Position(orig.Pos())
+	start := file.Offset(file.LineStart(pos.Line))
+
+	res := tabWidth
+	for i := start; i < len(s.content); i++ {
+		switch s.content[i] {
+		case '\t':
+			res += tabWidth
+		case ' ':
+			res++
+		default:
+			return res
+		}
+	}
+
+	return res
+}
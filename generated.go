@@ -0,0 +1,31 @@
+package fancyfmt
+
+import (
+	"go/parser"
+	"go/token"
+	"regexp"
+)
+
+var generatedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// IsGenerated checks if the source has a "// Code generated … DO NOT EDIT." line before the package clause
+func IsGenerated(src []byte) bool {
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		return false
+	}
+
+	for _, g := range file.Comments {
+		if g.Pos() > file.Package {
+			break
+		}
+
+		for _, c := range g.List {
+			if generatedHeader.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
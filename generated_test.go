@@ -0,0 +1,60 @@
+package fancyfmt_test
+
+import (
+	"testing"
+
+	"github.com/sirkon/fancyfmt"
+)
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		generated bool
+	}{
+		{
+			name:      "generated",
+			src:       "// Code generated by stringer. DO NOT EDIT.\n\npackage a\n",
+			generated: true,
+		},
+		{
+			name:      "after build constraint",
+			src:       "//go:build linux\n\n// Code generated by cgo -godefs; DO NOT EDIT.\n\npackage a\n",
+			generated: true,
+		},
+		{
+			name:      "in a comment group",
+			src:       "// Package a does things.\n// Code generated by hand. DO NOT EDIT.\npackage a\n",
+			generated: true,
+		},
+		{
+			name: "after the package clause",
+			src:  "package a\n\n// Code generated by stringer. DO NOT EDIT.\n",
+		},
+		{
+			name: "not at line start",
+			src:  "// This is not Code generated by stringer. DO NOT EDIT.\n\npackage a\n",
+		},
+		{
+			name: "no trailing dot",
+			src:  "// Code generated by stringer. DO NOT EDIT\n\npackage a\n",
+		},
+		{
+			name: "block comment",
+			src:  "/* Code generated by stringer. DO NOT EDIT. */\n\npackage a\n",
+		},
+		{
+			name: "not go",
+			src:  "// Code generated by stringer. DO NOT EDIT.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			if res := fancyfmt.IsGenerated([]byte(tt.src)); res != tt.generated {
+				t.Errorf("IsGenerated = %t, expected %t", res, tt.generated)
+			}
+		})
+	}
+}
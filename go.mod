@@ -8,12 +8,12 @@ require (
 	github.com/sirkon/errors v0.2.0
 	github.com/sirkon/jsonexec v0.0.1
 	github.com/sirkon/message v1.5.1
+	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4
 	golang.org/x/tools v0.1.12
 )
 
 require (
 	github.com/pkg/errors v0.8.1 // indirect
 	github.com/stretchr/testify v1.7.1 // indirect
-	golang.org/x/mod v0.6.0-dev.0.20220419223038-86c51ed26bb4 // indirect
 	golang.org/x/sys v0.0.0-20220722155257-8c9f86f7a55f // indirect
 )
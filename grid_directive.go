@@ -0,0 +1,178 @@
+package fancyfmt
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/dave/dst"
+	"github.com/sirkon/errors"
+)
+
+// directiveGrid sets a layout of a composite literal, like
+//
+//	//fancyfmt:grid cols=8 hex ascii
+//
+// It is set either right after the opening brace of the literal or before a declaration or a statement, the first
+// composite literal within is used then.
+const directiveGrid = "//fancyfmt:grid"
+
+// gridDirective parameters of grid directive
+type gridDirective struct {
+	cols  int  // a number of columns, the default layout is used if it is 0
+	base  int  // a base integers are rendered in, they are kept as is if it is 0
+	ascii bool // annotate rows of bytes with their printable ASCII characters
+}
+
+// parseGridDirective parses the comment into a grid directive. False is returned if this is not a grid directive.
+func parseGridDirective(comment string) (gridDirective, bool, error) {
+	if !isDirective(comment, directiveGrid) {
+		return gridDirective{}, false, nil
+	}
+
+	var res gridDirective
+	for _, param := range strings.Fields(comment[len(directiveGrid):]) {
+		switch param {
+		case "hex":
+			res.base = 16
+		case "dec":
+			res.base = 10
+		case "oct":
+			res.base = 8
+		case "bin":
+			res.base = 2
+		case "ascii":
+			res.ascii = true
+		default:
+			if !strings.HasPrefix(param, "cols=") {
+				return gridDirective{}, true, errors.Newf("unknown grid parameter %q", param)
+			}
+
+			cols, err := strconv.Atoi(strings.TrimPrefix(param, "cols="))
+			if err != nil || cols < 1 {
+				return gridDirective{}, true, errors.Newf("invalid number of columns in %q", param)
+			}
+			res.cols = cols
+		}
+	}
+
+	return res, true, nil
+}
+
+// findGridDirective looks for a grid directive in decorations
+func findGridDirective(decs dst.Decorations) (gridDirective, bool, error) {
+	for _, d := range decs {
+		res, ok, err := parseGridDirective(d)
+		if ok || err != nil {
+			return res, ok, err
+		}
+	}
+
+	return gridDirective{}, false, nil
+}
+
+// collectGridDirectives finds composite literals grid directives are set for
+func collectGridDirectives(file *dst.File, st *formatState) (map[*dst.CompositeLit]gridDirective, error) {
+	res := map[*dst.CompositeLit]gridDirective{}
+	var errs []error
+	dst.Inspect(file, func(node dst.Node) bool {
+		if node == nil {
+			return true
+		}
+
+		d, ok, err := findGridDirective(node.Decorations().Start)
+		if !ok {
+			if l, isLit := node.(*dst.CompositeLit); isLit {
+				d, ok, err = findGridDirective(l.Decs.Lbrace)
+			}
+		}
+		if err != nil {
+			errs = append(errs, errors.Wrapf(err, "line %d", st.line(node)))
+			return true
+		}
+		if !ok {
+			return true
+		}
+
+		var found bool
+		dst.Inspect(node, func(n dst.Node) bool {
+			if found {
+				return false
+			}
+			l, isLit := n.(*dst.CompositeLit)
+			if !isLit {
+				return true
+			}
+
+			found = true
+			if _, set := res[l]; !set {
+				res[l] = d
+			}
+			return false
+		})
+
+		return true
+	})
+
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+
+	return res, nil
+}
+
+// directedGrid lays out the literal as the grid directive tells. False is returned if this is not a literal of an
+// array or a slice, the directive does not apply to it then.
+func directedGrid(l *dst.CompositeLit, d gridDirective, typ literalType, st *formatState) bool {
+	if !typ.array {
+		return false
+	}
+	if len(l.Elts) == 0 {
+		return true
+	}
+	for _, e := range l.Elts {
+		if len(e.Decorations().Start) > 0 || len(e.Decorations().End) > 0 {
+			// comments keep the layout as is
+			return true
+		}
+	}
+
+	_, _, integers := integerLiteralsBase(l)
+	if integers && d.base != 0 && (d.base == 10 || !hasNegativeIntegers(l)) {
+		switch d.base {
+		case 16:
+			padIntegers(l, 16, "0x", hexWidths[typ.elem])
+		case 10:
+			padIntegers(l, 10, "", 0)
+		case 8:
+			prefix := "0o"
+			if !goVersionAtLeast(st.opts.goVersion, 13) {
+				prefix = "0"
+			}
+			padIntegers(l, 8, prefix, 0)
+		case 2:
+			// binary literals need Go 1.13, integers are kept as is for older modules
+			if goVersionAtLeast(st.opts.goVersion, 13) {
+				padIntegers(l, 2, "0b", 0)
+			}
+		}
+	}
+
+	switch {
+	case d.cols > 0:
+		gridLayout(l, d.cols)
+	case ensureFormat(l):
+	case integers && st.opts.fittedGrids:
+		gridLayout(l, integerGridColumns(l, typ, st))
+	default:
+		gridLayout(l, squareColumns(l))
+	}
+
+	if (d.ascii || st.opts.byteASCII) && isByteType(typ.elem) {
+		annotateBytes(l)
+	}
+	if integers && st.opts.numberAlignment {
+		st.aligns[l] = alignGridRight
+	}
+
+	return true
+}
@@ -0,0 +1,80 @@
+package fancyfmt
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+
+	"github.com/sirkon/errors"
+
+	"github.com/sirkon/fancyfmt/internal/diff"
+)
+
+// checkIdempotence formats the formatted source once more and checks nothing changes
+func checkIdempotence(orig *ast.File, formatted []byte, grouper ImportsGrouper, opts *options) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", formatted, parser.ParseComments)
+	if err != nil {
+		return errors.Wrap(err, "parse formatted source")
+	}
+
+	again := *opts
+	if opts.typesInfo != nil {
+		again.typesInfo = literalTypesInfo(opts.typesInfo, orig, file)
+	}
+	res, err := formatFile(fset, file, formatted, grouper, &again)
+	if err != nil {
+		return errors.Wrap(err, "format formatted source")
+	}
+
+	before := diff.SplitLines(string(formatted))
+	after := diff.SplitLines(string(res))
+	for i := 0; i < len(before) || i < len(after); i++ {
+		var b, a string
+		if i < len(before) {
+			b = before[i]
+		}
+		if i < len(after) {
+			a = after[i]
+		}
+		if a != b {
+			return errors.Newf("line %d changes on the second pass: %q turned into %q", i+1, b, a)
+		}
+	}
+
+	return nil
+}
+
+// literalTypesInfo carries types of composite literals of the original file over the formatted one. Formatting does
+// not add or remove literals, so they are matched by their order.
+func literalTypesInfo(info *types.Info, orig *ast.File, formatted *ast.File) *types.Info {
+	origLits := compositeLits(orig)
+	lits := compositeLits(formatted)
+	if len(origLits) != len(lits) {
+		return nil
+	}
+
+	res := &types.Info{
+		Types: map[ast.Expr]types.TypeAndValue{},
+	}
+	for i, l := range origLits {
+		if tv, ok := info.Types[l]; ok {
+			res.Types[lits[i]] = tv
+		}
+	}
+
+	return res
+}
+
+func compositeLits(file *ast.File) []*ast.CompositeLit {
+	var res []*ast.CompositeLit
+	ast.Inspect(file, func(node ast.Node) bool {
+		if l, ok := node.(*ast.CompositeLit); ok {
+			res = append(res, l)
+		}
+		return true
+	})
+
+	return res
+}
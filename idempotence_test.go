@@ -0,0 +1,53 @@
+package fancyfmt
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestCheckIdempotence(t *testing.T) {
+	tests := []struct {
+		name      string
+		formatted string // a result of the first pass
+		err       string // a part of the expected error, empty if the second pass keeps the result
+	}{
+		{
+			name:      "stable",
+			formatted: "package a\n\nvar x = []int{\n\t1,\n\t2,\n}\n",
+		},
+		{
+			name:      "multiline literal",
+			formatted: "package a\n\nvar x = []int{1,\n\t2}\n",
+			err:       `line 3 changes on the second pass: "var x = []int{1,\n" turned into "var x = []int{\n"`,
+		},
+		{
+			name:      "imports",
+			formatted: "package a\n\nimport \"os\"\nimport \"fmt\"\n",
+			err:       `line 3 changes on the second pass: "import \"os\"\n" turned into "import (\n"`,
+		},
+	}
+
+	grouper := DefaultImportGroupsWithCurrent("")
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := parser.ParseFile(token.NewFileSet(), "", tt.formatted, parser.ParseComments)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the formatted source is passed as the original file, only the second pass matters here
+			err = checkIdempotence(file, []byte(tt.formatted), grouper, newOptions(nil))
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("unexpected error: %s", err)
+			case tt.err != "" && err == nil:
+				t.Errorf("expected error %q, got none", tt.err)
+			case tt.err != "" && !strings.Contains(err.Error(), tt.err):
+				t.Errorf("expected error %q, got %q", tt.err, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+// Package diff computes line edits turning one text into another.
+package diff
+
+import (
+	"strings"
+)
+
+// Edit replaces lines from Start up to End (excluded) of the original text with Text. Start == End means an
+// insertion before the Start line. Lines are counted from 0.
+type Edit struct {
+	Start int
+	End   int
+	Text  string
+}
+
+// maxTableSize a limit of the LCS table size, the whole differing part is replaced with a single edit above it
+const maxTableSize = 4 * 1024 * 1024
+
+// Lines returns minimal line edits turning before into after. Edits are ordered and do not overlap.
+func Lines(before string, after string) []Edit {
+	a := SplitLines(before)
+	b := SplitLines(after)
+
+	// trim common prefix and suffix, changes of formatting are usually local
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a = a[prefix : len(a)-suffix]
+	b = b[prefix : len(b)-suffix]
+
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	if len(a) == 0 || len(b) == 0 || (len(a)+1)*(len(b)+1) > maxTableSize {
+		return []Edit{
+			{
+				Start: prefix,
+				End:   prefix + len(a),
+				Text:  strings.Join(b, ""),
+			},
+		}
+	}
+
+	// lcs[i][j] is a length of the longest common subsequence of a[i:] and b[j:]
+	width := len(b) + 1
+	lcs := make([]int32, (len(a)+1)*width)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i*width+j] = lcs[(i+1)*width+j+1] + 1
+			case lcs[(i+1)*width+j] >= lcs[i*width+j+1]:
+				lcs[i*width+j] = lcs[(i+1)*width+j]
+			default:
+				lcs[i*width+j] = lcs[i*width+j+1]
+			}
+		}
+	}
+
+	var res []Edit
+	var cur *Edit
+	flush := func() {
+		if cur != nil {
+			res = append(res, *cur)
+			cur = nil
+		}
+	}
+	edit := func(i int) *Edit {
+		if cur == nil {
+			cur = &Edit{
+				Start: prefix + i,
+				End:   prefix + i,
+			}
+		}
+		return cur
+	}
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			flush()
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i*width+j+1] >= lcs[(i+1)*width+j]):
+			e := edit(i)
+			e.Text += b[j]
+			j++
+		default:
+			e := edit(i)
+			e.End++
+			i++
+		}
+	}
+	flush()
+
+	return res
+}
+
+// SplitLines splits the text into lines keeping line ends. The last line has no line end if the text does not end
+// with it.
+func SplitLines(text string) []string {
+	var res []string
+	for len(text) > 0 {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			res = append(res, text)
+			break
+		}
+
+		res = append(res, text[:i+1])
+		text = text[i+1:]
+	}
+
+	return res
+}
@@ -0,0 +1,97 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		before string
+		after  string
+		edits  []Edit
+	}{
+		{
+			name:   "same",
+			before: "a\nb\n",
+			after:  "a\nb\n",
+		},
+		{
+			name:   "insert at end",
+			before: "a\nb\n",
+			after:  "a\nb\nc\n",
+			edits:  []Edit{{Start: 2, End: 2, Text: "c\n"}},
+		},
+		{
+			name:   "insert at start",
+			before: "b\nc\n",
+			after:  "a\nb\nc\n",
+			edits:  []Edit{{Start: 0, End: 0, Text: "a\n"}},
+		},
+		{
+			name:   "no trailing newline",
+			before: "a\nb",
+			after:  "a\nb\n",
+			edits:  []Edit{{Start: 1, End: 2, Text: "b\n"}},
+		},
+		{
+			name:   "trailing newline removed",
+			before: "a\nb\n",
+			after:  "a\nb",
+			edits:  []Edit{{Start: 1, End: 2, Text: "b"}},
+		},
+		{
+			name:   "prefix and suffix trimmed",
+			before: "a\nb\nc\nd\ne\n",
+			after:  "a\nb\nx\nd\ne\n",
+			edits:  []Edit{{Start: 2, End: 3, Text: "x\n"}},
+		},
+		{
+			name:   "deletion",
+			before: "a\nb\nc\n",
+			after:  "a\nc\n",
+			edits:  []Edit{{Start: 1, End: 2}},
+		},
+		{
+			name:   "separate changes",
+			before: "a\nb\nc\nd\ne\n",
+			after:  "a\nB\nc\nD\ne\n",
+			edits:  []Edit{{Start: 1, End: 2, Text: "B\n"}, {Start: 3, End: 4, Text: "D\n"}},
+		},
+		{
+			name:   "from empty",
+			before: "",
+			after:  "a\n",
+			edits:  []Edit{{Start: 0, End: 0, Text: "a\n"}},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			edits := Lines(tt.before, tt.after)
+			if !reflect.DeepEqual(edits, tt.edits) {
+				t.Errorf("unexpected edits %+v, expected %+v", edits, tt.edits)
+			}
+			if res := apply(tt.before, edits); res != tt.after {
+				t.Errorf("edits turn the text into %q, expected %q", res, tt.after)
+			}
+		})
+	}
+}
+
+func apply(text string, edits []Edit) string {
+	lines := SplitLines(text)
+	var buf strings.Builder
+	last := 0
+	for _, e := range edits {
+		buf.WriteString(strings.Join(lines[last:e.Start], ""))
+		buf.WriteString(e.Text)
+		last = e.End
+	}
+	buf.WriteString(strings.Join(lines[last:], ""))
+
+	return buf.String()
+}
@@ -0,0 +1,360 @@
+// Package lsp implements a Language Server Protocol server providing fancyfmt formatting over stdio.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/sirkon/errors"
+
+	"github.com/sirkon/fancyfmt"
+	"github.com/sirkon/fancyfmt/internal/diff"
+)
+
+// Server formats documents an editor opens with the given options. Import groupers are resolved per workspace
+// folder from its go.mod file.
+type Server struct {
+	opts     []fancyfmt.Option
+	docs     map[string]string
+	folders  []string
+	groupers map[string]fancyfmt.ImportsGrouper
+	shutdown bool
+}
+
+// NewServer creates a server formatting with the given options
+func NewServer(opts []fancyfmt.Option) *Server {
+	return &Server{
+		opts:     opts,
+		docs:     map[string]string{},
+		groupers: map[string]fancyfmt.ImportsGrouper{},
+	}
+}
+
+// Serve handles messages from in and writes responses into out until the exit notification or the end of input
+func (s *Server) Serve(in io.Reader, out io.Writer) error {
+	r := bufio.NewReader(in)
+	for {
+		data, err := readMessage(r)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return errors.Wrap(err, "read message")
+		}
+
+		var req request
+		if err := json.Unmarshal(data, &req); err != nil {
+			if err := writeMessage(out, response{
+				JSONRPC: "2.0",
+				Error: &responseError{
+					Code:    codeParseError,
+					Message: err.Error(),
+				},
+			}); err != nil {
+				return errors.Wrap(err, "write response")
+			}
+			continue
+		}
+
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+
+		result, err := s.handle(req.Method, req.Params)
+		if req.ID == nil {
+			// notifications have no responses
+			continue
+		}
+
+		resp := response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+		}
+		if err != nil {
+			var rerr *responseError
+			if !errors.As(err, &rerr) {
+				rerr = &responseError{
+					Code:    codeInternalError,
+					Message: err.Error(),
+				}
+			}
+			resp.Error = rerr
+		} else {
+			resp.Result, err = json.Marshal(result)
+			if err != nil {
+				return errors.Wrap(err, "encode result of "+req.Method)
+			}
+		}
+
+		if err := writeMessage(out, resp); err != nil {
+			return errors.Wrap(err, "write response")
+		}
+	}
+}
+
+func (s *Server) handle(method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case "initialize":
+		var p initializeParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		for _, f := range p.WorkspaceFolders {
+			s.addFolder(f.URI)
+		}
+		if len(p.WorkspaceFolders) == 0 && p.RootURI != "" {
+			s.addFolder(p.RootURI)
+		}
+
+		return initializeResult{
+			Capabilities: serverCapabilities{
+				TextDocumentSync:                textDocumentSyncFull,
+				DocumentFormattingProvider:      true,
+				DocumentRangeFormattingProvider: true,
+				Workspace: workspaceCapabilities{
+					WorkspaceFolders: workspaceFoldersCapabilities{
+						Supported:           true,
+						ChangeNotifications: true,
+					},
+				},
+			},
+			ServerInfo: serverInfo{
+				Name: "fancyfmt",
+			},
+		}, nil
+
+	case "initialized":
+		return nil, nil
+
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+
+	case "workspace/didChangeWorkspaceFolders":
+		var p didChangeWorkspaceFoldersParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		for _, f := range p.Event.Removed {
+			s.removeFolder(f.URI)
+		}
+		for _, f := range p.Event.Added {
+			s.addFolder(f.URI)
+		}
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var p didOpenTextDocumentParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		s.docs[p.TextDocument.URI] = p.TextDocument.Text
+		return nil, nil
+
+	case "textDocument/didChange":
+		var p didChangeTextDocumentParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if len(p.ContentChanges) > 0 {
+			// full sync, the last change is the actual content
+			s.docs[p.TextDocument.URI] = p.ContentChanges[len(p.ContentChanges)-1].Text
+		}
+		return nil, nil
+
+	case "textDocument/didClose":
+		var p didCloseTextDocumentParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		delete(s.docs, p.TextDocument.URI)
+		return nil, nil
+
+	case "textDocument/formatting":
+		var p documentFormattingParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.format(p.TextDocument.URI, nil)
+
+	case "textDocument/rangeFormatting":
+		var p documentRangeFormattingParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.format(p.TextDocument.URI, &p.Range)
+
+	default:
+		return nil, &responseError{
+			Code:    codeMethodNotFound,
+			Message: "method not supported: " + method,
+		}
+	}
+}
+
+// format formats the document or its range and returns edits turning the document into the formatted one
+func (s *Server) format(uri string, rng *textRange) ([]textEdit, error) {
+	content, ok := s.docs[uri]
+	if !ok {
+		return nil, &responseError{
+			Code:    codeInvalidParams,
+			Message: "document is not open: " + uri,
+		}
+	}
+
+	path, err := uriPath(uri)
+	if err != nil {
+		return nil, err
+	}
+	grouper, err := s.grouper(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "get imports grouper")
+	}
+
+	// a version given with options overrides the one of the module
+	opts := s.opts
+	if version, err := fancyfmt.GoVersionForDir(filepath.Dir(path)); err == nil && version != "" {
+		opts = append([]fancyfmt.Option{fancyfmt.WithGoVersion(version)}, s.opts...)
+	}
+
+	var res []byte
+	if rng != nil {
+		// the range end is exclusive, a line it points to the start of is not included
+		start, end := rng.Start.Line+1, rng.End.Line+1
+		if rng.End.Character == 0 && rng.End.Line > rng.Start.Line {
+			end--
+		}
+		res, err = fancyfmt.FormatRange([]byte(content), start, end, grouper, opts...)
+	} else {
+		res, err = fancyfmt.Source([]byte(content), grouper, opts...)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lines := diff.SplitLines(content)
+	edits := []textEdit{}
+	for _, e := range diff.Lines(content, string(res)) {
+		edits = append(edits, textEdit{
+			Range: textRange{
+				Start: linePosition(lines, e.Start),
+				End:   linePosition(lines, e.End),
+			},
+			NewText: e.Text,
+		})
+	}
+
+	return edits, nil
+}
+
+// grouper returns an imports grouper of the workspace folder the file belongs to or of the file's module if there's
+// no such folder or its module cannot be found.
+func (s *Server) grouper(path string) (fancyfmt.ImportsGrouper, error) {
+	dir := filepath.Dir(path)
+	var folder string
+	for _, f := range s.folders {
+		if (dir == f || strings.HasPrefix(dir, f+string(filepath.Separator))) && len(f) > len(folder) {
+			folder = f
+		}
+	}
+
+	for _, d := range []string{folder, dir} {
+		if d == "" {
+			continue
+		}
+
+		if g, ok := s.groupers[d]; ok {
+			return g, nil
+		}
+		g, err := fancyfmt.ImportsGrouperForDir(d)
+		if err != nil {
+			if d == folder {
+				continue
+			}
+			return nil, errors.Wrap(err, "look for module of "+d)
+		}
+
+		s.groupers[d] = g
+		return g, nil
+	}
+
+	return nil, errors.New("no module found for " + path)
+}
+
+func (s *Server) addFolder(uri string) {
+	path, err := uriPath(uri)
+	if err != nil {
+		return
+	}
+
+	s.folders = append(s.folders, path)
+}
+
+func (s *Server) removeFolder(uri string) {
+	path, err := uriPath(uri)
+	if err != nil {
+		return
+	}
+
+	for i, f := range s.folders {
+		if f == path {
+			s.folders = append(s.folders[:i], s.folders[i+1:]...)
+			delete(s.groupers, path)
+			return
+		}
+	}
+}
+
+// linePosition returns a position of the start of the given line. The end of the text is returned for lines past
+// the last one.
+func linePosition(lines []string, line int) position {
+	if line < len(lines) || len(lines) == 0 || strings.HasSuffix(lines[len(lines)-1], "\n") {
+		return position{
+			Line: line,
+		}
+	}
+
+	// the last line has no line end
+	last := lines[len(lines)-1]
+	return position{
+		Line:      len(lines) - 1,
+		Character: len(utf16.Encode([]rune(last))),
+	}
+}
+
+func uriPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", &responseError{
+			Code:    codeInvalidParams,
+			Message: "invalid document uri " + uri,
+		}
+	}
+	if u.Scheme != "file" {
+		return "", &responseError{
+			Code:    codeInvalidParams,
+			Message: "not a file uri " + uri,
+		}
+	}
+
+	return filepath.FromSlash(u.Path), nil
+}
+
+func decodeParams(params json.RawMessage, dst interface{}) error {
+	if err := json.Unmarshal(params, dst); err != nil {
+		return &responseError{
+			Code:    codeInvalidParams,
+			Message: err.Error(),
+		}
+	}
+
+	return nil
+}
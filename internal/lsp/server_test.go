@@ -0,0 +1,179 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/sirkon/fancyfmt"
+	"github.com/sirkon/fancyfmt/internal/diff"
+)
+
+const testSource = `package example
+
+import (
+	"os"
+	"fmt"
+)
+
+func f() {
+	fmt.Println(os.Args,
+		1)
+}
+
+func g() {
+	fmt.Println(os.Args,
+		2)
+}
+`
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	uri := "file://" + filepath.ToSlash(filepath.Join(dir, "example.go"))
+
+	var in bytes.Buffer
+	send := func(id int, method string, params interface{}) {
+		t.Helper()
+
+		data, err := json.Marshal(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := request{
+			JSONRPC: "2.0",
+			Method:  method,
+			Params:  data,
+		}
+		if id > 0 {
+			rawID := json.RawMessage(strconv.Itoa(id))
+			req.ID = &rawID
+		}
+		if err := writeMessage(&in, req); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send(1, "initialize", map[string]interface{}{
+		"rootUri": "file://" + filepath.ToSlash(dir),
+	})
+	send(0, "initialized", map[string]interface{}{})
+	send(0, "textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{
+			"uri":  uri,
+			"text": testSource,
+		},
+	})
+	send(2, "textDocument/formatting", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+	})
+	send(3, "textDocument/rangeFormatting", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri},
+		"range": textRange{
+			Start: position{Line: 7},
+			End:   position{Line: 11},
+		},
+	})
+	send(4, "shutdown", nil)
+	send(0, "exit", nil)
+
+	var out bytes.Buffer
+	if err := NewServer(nil).Serve(&in, &out); err != nil {
+		t.Fatalf("serve: %s", err)
+	}
+
+	responses := map[string]response{}
+	r := bufio.NewReader(&out)
+	for {
+		data, err := readMessage(r)
+		if err != nil {
+			break
+		}
+
+		var resp response
+		if err := json.Unmarshal(data, &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Error != nil {
+			t.Fatalf("response %s: %s", *resp.ID, resp.Error.Message)
+		}
+		responses[string(*resp.ID)] = resp
+	}
+	if len(responses) != 4 {
+		t.Fatalf("4 responses expected, got %d", len(responses))
+	}
+
+	var init initializeResult
+	if err := json.Unmarshal(responses["1"].Result, &init); err != nil {
+		t.Fatal(err)
+	}
+	if !init.Capabilities.DocumentFormattingProvider || !init.Capabilities.DocumentRangeFormattingProvider {
+		t.Errorf("formatting capabilities are not reported: %+v", init.Capabilities)
+	}
+
+	grouper := fancyfmt.DefaultImportGroupsWithCurrent("example.com/example")
+	expected, err := fancyfmt.Source([]byte(testSource), grouper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res := applyEdits(t, testSource, responses["2"].Result); res != string(expected) {
+		t.Errorf("unexpected formatting result:\n%s", res)
+	}
+
+	expectedRange, err := fancyfmt.FormatRange([]byte(testSource), 8, 11, grouper)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := applyEdits(t, testSource, responses["3"].Result)
+	if res != string(expectedRange) {
+		t.Errorf("unexpected range formatting result:\n%s", res)
+	}
+	if !strings.Contains(res, "fmt.Println(\n\t\tos.Args,\n\t\t1,\n\t)") {
+		t.Errorf("range formatting did not format the range:\n%s", res)
+	}
+	if !strings.Contains(res, "fmt.Println(os.Args,\n\t\t2)") {
+		t.Errorf("range formatting changed lines out of the range:\n%s", res)
+	}
+
+	if string(responses["4"].Result) != "null" {
+		t.Errorf("unexpected shutdown result %s", responses["4"].Result)
+	}
+}
+
+// applyEdits applies edits of the result, they are ordered and start at line starts or at the end of text
+func applyEdits(t *testing.T, text string, result json.RawMessage) string {
+	t.Helper()
+
+	var edits []textEdit
+	if err := json.Unmarshal(result, &edits); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := diff.SplitLines(text)
+	offset := func(p position) int {
+		res := 0
+		for _, l := range lines[:p.Line] {
+			res += len(l)
+		}
+		return res + p.Character
+	}
+
+	var buf strings.Builder
+	last := 0
+	for _, e := range edits {
+		start := offset(e.Range.Start)
+		buf.WriteString(text[last:start])
+		buf.WriteString(e.NewText)
+		last = offset(e.Range.End)
+	}
+	buf.WriteString(text[last:])
+
+	return buf.String()
+}
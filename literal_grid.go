@@ -0,0 +1,119 @@
+package fancyfmt
+
+import (
+	"go/token"
+	"unicode/utf8"
+
+	"github.com/dave/dst"
+)
+
+// isIntegerType checks if this is an integer type integer grids are made for
+func isIntegerType(elem string) bool {
+	switch elem {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return true
+	default:
+		return false
+	}
+}
+
+// literalGrid lays out a slice of short literals or identifiers of the same kind as a grid with as many columns
+// as fit into the line width. Columns are padded after printing, so items line up.
+func literalGrid(l *dst.CompositeLit, st *formatState) bool {
+	var kind token.Token
+	var width int
+	for i, e := range l.Elts {
+		if len(e.Decorations().Start) > 0 || len(e.Decorations().End) > 0 {
+			return false
+		}
+
+		k, w, ok := gridItem(e)
+		if !ok {
+			return false
+		}
+
+		if i == 0 {
+			kind = k
+		} else if k != kind {
+			return false
+		}
+
+		if w > width {
+			width = w
+		}
+	}
+
+	cols := fittingColumns(l, width, st)
+	if cols < 2 {
+		return false
+	}
+
+	gridLayout(l, cols)
+	if kind == token.FLOAT {
+		st.aligns[l] = alignGridRight
+	} else {
+		st.aligns[l] = alignGrid
+	}
+	return true
+}
+
+// fittingColumns returns how many items of the given width fit into the line width at the literal's indentation
+func fittingColumns(l *dst.CompositeLit, width int, st *formatState) int {
+	// every item but the last one in a row is followed by ", ", the last one is followed by ","
+	return (st.opts.lineWidth - st.indent(l) + 1) / (width + 2)
+}
+
+// gridItem returns a kind of grid item and its width
+func gridItem(e dst.Expr) (token.Token, int, bool) {
+	switch v := e.(type) {
+	case *dst.BasicLit:
+		switch v.Kind {
+		case token.INT, token.FLOAT, token.IMAG:
+			return token.FLOAT, utf8.RuneCountInString(v.Value), true
+		default:
+			return v.Kind, utf8.RuneCountInString(v.Value), true
+		}
+	case *dst.UnaryExpr:
+		if v.Op != token.SUB {
+			return token.ILLEGAL, 0, false
+		}
+
+		x, ok := v.X.(*dst.BasicLit)
+		if !ok || x.Kind != token.INT && x.Kind != token.FLOAT {
+			return token.ILLEGAL, 0, false
+		}
+
+		return token.FLOAT, utf8.RuneCountInString(x.Value) + 1, true
+	case *dst.Ident:
+		return token.IDENT, utf8.RuneCountInString(v.Name), true
+	case *dst.SelectorExpr:
+		x, ok := v.X.(*dst.Ident)
+		if !ok {
+			return token.ILLEGAL, 0, false
+		}
+
+		return token.IDENT, utf8.RuneCountInString(x.Name) + 1 + utf8.RuneCountInString(v.Sel.Name), true
+	default:
+		return token.ILLEGAL, 0, false
+	}
+}
+
+// gridLayout puts literal elements into rows of the given width
+func gridLayout(l *dst.CompositeLit, cols int) {
+	for i, el := range l.Elts {
+		switch {
+		case i == 0 && el.Decorations().Before == dst.EmptyLine:
+			// it separates elements from a leading comment
+		case i%cols == 0:
+			el.Decorations().Before = dst.NewLine
+		default:
+			el.Decorations().Before = dst.None
+		}
+
+		if i == len(l.Elts)-1 {
+			el.Decorations().After = dst.NewLine
+		} else {
+			el.Decorations().After = dst.None
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package fancyfmt
+
+import (
+	"go/ast"
+	"go/types"
+
+	"github.com/dave/dst"
+)
+
+// literalType describes a type of composite literal in the extent needed for grid formatting
+type literalType struct {
+	array bool   // this is an array or a slice literal
+	elem  string // name of the basic type of elements, empty if elements are not of a basic type
+}
+
+// resolveLiteralType resolves a type of the given composite literal. It is only done with the literal's type
+// expression when no types info is set, with types info named types, aliases, qualified types and elided types
+// of nested literals are resolved as well.
+func resolveLiteralType(l *dst.CompositeLit, opts *options, nodes map[dst.Node]ast.Node) literalType {
+	if opts.typesInfo != nil {
+		if res, ok := resolveLiteralTypeWithInfo(l, opts.typesInfo, nodes); ok {
+			return res
+		}
+	}
+
+	v, ok := l.Type.(*dst.ArrayType)
+	if !ok {
+		return literalType{}
+	}
+
+	res := literalType{
+		array: true,
+	}
+	if id, ok := v.Elt.(*dst.Ident); ok {
+		res.elem = id.Name
+		if res.elem == "byte" {
+			res.elem = "uint8"
+		}
+	}
+
+	return res
+}
+
+func resolveLiteralTypeWithInfo(l *dst.CompositeLit, info *types.Info, nodes map[dst.Node]ast.Node) (literalType, bool) {
+	node, ok := nodes[l].(ast.Expr)
+	if !ok {
+		return literalType{}, false
+	}
+
+	typ := info.TypeOf(node)
+	if typ == nil {
+		return literalType{}, false
+	}
+
+	var elem types.Type
+	switch v := typ.Underlying().(type) {
+	case *types.Slice:
+		elem = v.Elem()
+	case *types.Array:
+		elem = v.Elem()
+	default:
+		return literalType{}, true
+	}
+
+	res := literalType{
+		array: true,
+	}
+	if b, ok := elem.Underlying().(*types.Basic); ok {
+		res.elem = types.Typ[b.Kind()].Name()
+	}
+
+	return res, true
+}
@@ -0,0 +1,62 @@
+package fancyfmt
+
+import (
+	"go/token"
+
+	"github.com/dave/dst"
+)
+
+// matrixLayout puts every inner literal of a literal of arrays or slices at its own line and keeps the inner literal
+// in one line. Columns are aligned across rows after printing. Nothing is done if there are comments or inner
+// literals have composite elements.
+func matrixLayout(l *dst.CompositeLit, typ literalType, st *formatState) bool {
+	if !typ.array || len(l.Elts) == 0 {
+		return false
+	}
+
+	numeric := true
+	for _, e := range l.Elts {
+		row, ok := e.(*dst.CompositeLit)
+		if !ok || len(row.Elts) == 0 {
+			return false
+		}
+		if len(row.Decorations().Start) > 0 || len(row.Decorations().End) > 0 || len(row.Decs.Lbrace) > 0 {
+			return false
+		}
+
+		for _, ee := range row.Elts {
+			if len(ee.Decorations().Start) > 0 || len(ee.Decorations().End) > 0 {
+				return false
+			}
+
+			switch v := ee.(type) {
+			case *dst.CompositeLit, *dst.FuncLit, *dst.KeyValueExpr:
+				return false
+			case *dst.BasicLit:
+				numeric = numeric && v.Kind != token.STRING && v.Kind != token.CHAR
+			case *dst.UnaryExpr:
+			default:
+				numeric = false
+			}
+		}
+	}
+
+	for i, e := range l.Elts {
+		// an empty line before the first row is kept, it separates rows from a leading comment
+		if i > 0 || e.Decorations().Before != dst.EmptyLine {
+			e.Decorations().Before = dst.NewLine
+		}
+		e.Decorations().After = dst.NewLine
+		for _, ee := range e.(*dst.CompositeLit).Elts {
+			ee.Decorations().Before = dst.None
+			ee.Decorations().After = dst.None
+		}
+	}
+
+	if numeric {
+		st.aligns[l] = alignMatrixRight
+	} else {
+		st.aligns[l] = alignMatrix
+	}
+	return true
+}
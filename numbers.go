@@ -0,0 +1,131 @@
+package fancyfmt
+
+import (
+	"go/token"
+	"strconv"
+	"strings"
+
+	"github.com/dave/dst"
+)
+
+// normalizeNumber normalizes spelling of integer and float literals:
+//
+//   - prefixes are lowercase: 0x, 0b, 0o.
+//   - legacy octal literals like 0755 get 0o prefix.
+//   - hex digits are all lowercase or all uppercase.
+//   - exponents are lowercase.
+//   - digits of large decimal and hex integers are grouped with _.
+//
+// 0o prefix and digit separators need Go 1.13 and are not used for modules of older versions.
+func normalizeNumber(v *dst.BasicLit, opts *options) {
+	switch v.Kind {
+	case token.INT:
+		v.Value = normalizeInteger(v.Value, opts)
+	case token.FLOAT:
+		v.Value = normalizeFloat(v.Value, opts)
+	}
+}
+
+func normalizeInteger(value string, opts *options) string {
+	modern := goVersionAtLeast(opts.goVersion, 13)
+
+	prefix, digits := "", value
+	base := 10
+	if len(value) > 1 && value[0] == '0' {
+		switch value[1] {
+		case 'x', 'X':
+			prefix, digits, base = "0x", value[2:], 16
+		case 'b', 'B':
+			prefix, digits, base = "0b", value[2:], 2
+		case 'o', 'O':
+			prefix, digits, base = "0o", value[2:], 8
+		default:
+			prefix, digits, base = "0", value[1:], 8
+			if modern {
+				prefix = "0o"
+			}
+		}
+	}
+
+	if base == 16 {
+		digits = hexCase(digits, opts.upperHex)
+	}
+
+	if opts.digitSeparators && modern {
+		switch base {
+		case 10:
+			digits = groupDigits(strings.ReplaceAll(digits, "_", ""), 3, 5)
+		case 16:
+			digits = groupDigits(strings.ReplaceAll(digits, "_", ""), 4, 9)
+		}
+	}
+
+	return prefix + digits
+}
+
+func normalizeFloat(value string, opts *options) string {
+	if len(value) > 1 && value[0] == '0' && (value[1] == 'x' || value[1] == 'X') {
+		mantissa, exp := value[2:], ""
+		if i := strings.IndexAny(mantissa, "pP"); i >= 0 {
+			mantissa, exp = mantissa[:i], "p"+mantissa[i+1:]
+		}
+
+		return "0x" + hexCase(mantissa, opts.upperHex) + exp
+	}
+
+	return strings.ReplaceAll(value, "E", "e")
+}
+
+func hexCase(digits string, upper bool) string {
+	if upper {
+		return strings.ToUpper(digits)
+	}
+
+	return strings.ToLower(digits)
+}
+
+// groupDigits groups digits by the given size from the right if there are at least min of them
+func groupDigits(digits string, size int, min int) string {
+	if len(digits) < min {
+		return digits
+	}
+
+	var buf strings.Builder
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%size == 0 {
+			buf.WriteByte('_')
+		}
+		buf.WriteRune(d)
+	}
+
+	return buf.String()
+}
+
+// goVersionAtLeast checks if the given Go version like 1.18 is at least 1.minor. Empty version is considered
+// to be the recent one.
+func goVersionAtLeast(version string, minor int) bool {
+	if version == "" {
+		return true
+	}
+
+	version = strings.TrimPrefix(version, "go")
+	parts := strings.Split(version, ".")
+	if len(parts) < 2 {
+		return true
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return true
+	}
+	if major != 1 {
+		return major > 1
+	}
+
+	m, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return true
+	}
+
+	return m >= minor
+}
@@ -0,0 +1,250 @@
+package fancyfmt
+
+import (
+	"go/types"
+)
+
+// Option sets up an optional formatting behavior
+type Option func(o *options)
+
+type options struct {
+	chainBreakCalls int
+	chainKeepRoot   bool
+	tableLayout     bool
+	byteASCII       bool
+	wideHex         bool
+	typesInfo       *types.Info
+	literalGrids    bool
+	lineWidth       int
+
+	fittedGrids       bool
+	powerOfTwoColumns bool
+	numberAlignment   bool
+	matrixLayout      bool
+
+	numberNormalization bool
+	upperHex            bool
+	digitSeparators     bool
+	goVersion           string
+
+	lines []lineRange
+
+	equivalenceCheck bool
+	idempotenceCheck bool
+
+	sortUnions bool
+}
+
+// DefaultLineWidth a line width grids are fitted into by default
+const DefaultLineWidth = 120
+
+func newOptions(opts []Option) *options {
+	res := &options{
+		lineWidth: DefaultLineWidth,
+	}
+	for _, opt := range opts {
+		opt(res)
+	}
+
+	return res
+}
+
+// WithChainBreakAfter makes method chains having more than n calls to be multiline even if they were written
+// in one line.
+func WithChainBreakAfter(n int) Option {
+	return func(o *options) {
+		o.chainBreakCalls = n
+	}
+}
+
+// WithChainRootOnFirstLine keeps the first link of a multiline chain at the line of the chain root, i.e.
+//
+//	NewQuery().Where(cond).
+//		Limit(10).
+//		Build()
+//
+// instead of
+//
+//	NewQuery().
+//		Where(cond).
+//		Limit(10).
+//		Build()
+func WithChainRootOnFirstLine() Option {
+	return func(o *options) {
+		o.chainKeepRoot = true
+	}
+}
+
+// WithTableLayout lays out slices of keyed struct literals having one element per line as aligned columns:
+//
+//	tests := []struct {
+//		name string
+//		in   int
+//		want int
+//	}{
+//		{name: "zero",     in: 0,  want: 0},
+//		{name: "negative", in: -1, want: 1},
+//	}
+//
+// Elements having comments or multiline values are left as is.
+func WithTableLayout() Option {
+	return func(o *options) {
+		o.tableLayout = true
+	}
+}
+
+// WithByteASCII adds a hexdump-like comment with printable ASCII characters at the end of each row of byte
+// literals grids:
+//
+//	var hello = []byte{
+//		0x48, 0x65, 0x6c, 0x6c, // |Hell|
+//		0x6f, 0x0a, // |o.|
+//	}
+//
+// These comments are regenerated on every run and do not freeze the layout as other comments do.
+func WithByteASCII() Option {
+	return func(o *options) {
+		o.byteASCII = true
+	}
+}
+
+// WithWideHex renders uint16, uint32 and uint64 literals grids as hex numbers zero-padded to their natural width
+// of 4, 8 and 16 digits respectively, the same way it is done for bytes. Signed integers are kept decimal.
+func WithWideHex() Option {
+	return func(o *options) {
+		o.wideHex = true
+	}
+}
+
+// WithTypesInfo resolves types of array and slice literals with the given types info, so named types, aliases,
+// qualified types and elided types of nested literals get the same grid and hex treatment as types written
+// as bare identifiers. The info must be collected for the very AST passed into Format and have Types recorded.
+func WithTypesInfo(info *types.Info) Option {
+	return func(o *options) {
+		o.typesInfo = info
+	}
+}
+
+// WithLiteralGrids lays out multiline slices of short literals or identifiers of the same kind, like strings,
+// runes, floats or enum constants, as grids with as many columns as fit into the line width. Columns are padded,
+// so items line up:
+//
+//	var allowed = []string{
+//		"a",    "bb",    "ccc",  "dddd",
+//		"eeee", "fffff", "gggg",
+//	}
+func WithLiteralGrids() Option {
+	return func(o *options) {
+		o.literalGrids = true
+	}
+}
+
+// WithLineWidth sets a line width grids are fitted into, DefaultLineWidth is used if it was not set.
+func WithLineWidth(width int) Option {
+	return func(o *options) {
+		o.lineWidth = width
+	}
+}
+
+// WithFittedGrids computes a number of columns of integer grids from the line width and the width of their widest
+// element instead of fitting them into a square.
+func WithFittedGrids() Option {
+	return func(o *options) {
+		o.fittedGrids = true
+	}
+}
+
+// WithPowerOfTwoColumns makes fitted integer grids to have a number of columns that is a power of two.
+func WithPowerOfTwoColumns() Option {
+	return func(o *options) {
+		o.powerOfTwoColumns = true
+	}
+}
+
+// WithNumberAlignment right-aligns columns of integer grids, so digits line up:
+//
+//	var coeffs = []int{
+//		  -1,  20, -300,
+//		4000,  -5,    6,
+//	}
+func WithNumberAlignment() Option {
+	return func(o *options) {
+		o.numberAlignment = true
+	}
+}
+
+// WithMatrixLayout lays out multiline literals of arrays or slices, like [4][4]int{…} or [][]float64{…}, as matrices:
+// every inner literal stays on one line and columns are aligned across rows:
+//
+//	var m = [3][3]int{
+//		{ 1,  0, -10},
+//		{ 0, 10,   0},
+//		{-1,  0,   1},
+//	}
+//
+// Literals having comments are formatted as usual.
+func WithMatrixLayout() Option {
+	return func(o *options) {
+		o.matrixLayout = true
+	}
+}
+
+// WithNumberNormalization normalizes spelling of integer and float literals: prefixes are lowercase, legacy octal
+// literals get 0o prefix, exponents are lowercase and hex digits are lowercase or uppercase if upperHex is set.
+// Digits of large decimal and hex integers are grouped with _ if separators is set:
+//
+//	1_000_000, 0x1_0000_0000
+//
+// 0o prefix and digit separators need Go 1.13, use WithGoVersion to not have them for modules of older versions.
+func WithNumberNormalization(upperHex bool, separators bool) Option {
+	return func(o *options) {
+		o.numberNormalization = true
+		o.upperHex = upperHex
+		o.digitSeparators = separators
+	}
+}
+
+// WithGoVersion sets a Go version of the module like 1.18, it is used to not produce code a module's Go version
+// does not support.
+func WithGoVersion(version string) Option {
+	return func(o *options) {
+		o.goVersion = version
+	}
+}
+
+// WithLines limits formatting rules to nodes overlapping lines from start to end of the source, both are included
+// and counted from 1. It may be used several times to set several ranges. Imports are grouped regardless of it.
+func WithLines(start int, end int) Option {
+	return func(o *options) {
+		o.lines = append(o.lines, lineRange{
+			start: start,
+			end:   end,
+		})
+	}
+}
+
+// WithEquivalenceCheck makes formatting to parse its result and compare it with the original AST. Positions, places
+// of comments, the grouping of imports and the spelling of literals may differ, anything else is an error reporting
+// the original node that differs.
+func WithEquivalenceCheck() Option {
+	return func(o *options) {
+		o.equivalenceCheck = true
+	}
+}
+
+// WithIdempotenceCheck makes formatting to format its result once more and to fail if this changes anything. The
+// check is not done when formatting is limited with WithLines.
+func WithIdempotenceCheck() Option {
+	return func(o *options) {
+		o.idempotenceCheck = true
+	}
+}
+
+// WithSortedUnions makes terms of constraint unions of interface elements to be sorted by their type names:
+//
+//	~int | ~int16 | ~int32 | ~int64 | ~int8
+func WithSortedUnions() Option {
+	return func(o *options) {
+		o.sortUnions = true
+	}
+}
@@ -0,0 +1,36 @@
+package fancyfmt
+
+import (
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io"
+
+	"github.com/sirkon/errors"
+)
+
+// Source formats the source the way fancyfmt command does: gofmt is applied first and then Format
+func Source(src []byte, grouper ImportsGrouper, opts ...Option) ([]byte, error) {
+	src, err := format.Source(src)
+	if err != nil {
+		return nil, errors.Wrap(err, "apply standard formatting")
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.AllErrors|parser.ParseComments)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse source")
+	}
+
+	formatted, err := Format(fset, file, src, grouper, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := io.ReadAll(formatted)
+	if err != nil {
+		return nil, errors.Wrap(err, "read formatted source")
+	}
+
+	return res, nil
+}